@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minibackend/ids"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The `apiKeyPrefix` constant starts every API key, which tells `authenticate` that a bearer token is
+// an API key rather than a session token. A key has the form mbk_<ID>_<secret>, so it can be looked up
+// by its ID and then compared against the stored hash.
+const apiKeyPrefix = "mbk_"
+
+// The `lastUsedResolution` constant is how precisely the last use of an API key is recorded. A key
+// used again within this time is not written back, so a busy script does not write the store on every
+// request.
+const lastUsedResolution = time.Minute
+
+// The `boardPatterns` variable holds the route patterns that work on the data of a board. It is
+// filled in `main` and tells `checkAPIKey` which routes a key restricted to a board may use.
+var boardPatterns = map[string]bool{}
+
+// The `apiKeyView` struct is how an API key is shown to clients. It leaves out the hash of the key.
+type apiKeyView struct {
+	ID_key   string           `json:"ID_key"`
+	Name     string           `json:"name"`
+	Prefix   string           `json:"prefix"`
+	User     string           `json:"user"`
+	Board    string           `json:"board,omitempty"`
+	Scope    structures.Scope `json:"scope"`
+	Created  time.Time        `json:"created"`
+	Expires  *time.Time       `json:"expires_at"`
+	LastUsed *time.Time       `json:"last_used_at"`
+}
+
+func viewOfKey(k *structures.APIKey) apiKeyView {
+	v := apiKeyView{ID_key: k.ID_key, Name: k.Name, Prefix: k.Prefix, User: k.User, Board: k.Board, Scope: k.Scope, Created: k.Created}
+	if !k.Expires.IsZero() {
+		v.Expires = &k.Expires
+	}
+	if !k.LastUsed.IsZero() {
+		v.LastUsed = &k.LastUsed
+	}
+	return v
+}
+
+// The `listAPIKeys` function handles `GET /api-keys`. It serves every API key, ordered by the time it
+// was created, with its expiry and the time it was last used.
+func listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var allKeys map[string]*structures.APIKey
+	err := store.View(func(tx storage.Tx) error {
+		var err error
+		allKeys, err = tx.APIKeys()
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list := make([]apiKeyView, 0, len(allKeys))
+	for _, k := range allKeys {
+		list = append(list, viewOfKey(k))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Created.Equal(list[j].Created) {
+			return list[i].Created.Before(list[j].Created)
+		}
+		return list[i].ID_key < list[j].ID_key
+	})
+	writeJSON(w, http.StatusOK, list)
+}
+
+// The `createAPIKey` function handles `POST /api-keys`. The body names the key and may set the `user`
+// the key acts as (default: the admin creating it), a `board` the key is restricted to, the `scope`
+// ("read" by default, or "write") and an `expires_at` time. The response is 201 Created with the key
+// itself in `key`; it is only stored as a hash and cannot be shown again.
+func createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name    string           `json:"name"`
+		User    string           `json:"user"`
+		Board   string           `json:"board"`
+		Scope   structures.Scope `json:"scope"`
+		Expires *time.Time       `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	key := &structures.APIKey{
+		Name:    body.Name,
+		User:    body.User,
+		Board:   body.Board,
+		Scope:   body.Scope,
+		Created: time.Now().UTC(),
+	}
+	if key.User == "" {
+		if user := currentUser(r); user != nil {
+			key.User = user.ID_user
+		}
+	}
+	if key.Scope == "" {
+		key.Scope = structures.ScopeRead
+	}
+	if body.Expires != nil {
+		key.Expires = body.Expires.UTC()
+	}
+
+	var token string
+	err := store.Update(func(tx storage.Tx) error {
+		users, err := tx.Users()
+		if err != nil {
+			return err
+		}
+		if errs := validation.APIKey(key, users, key.Created); errs != nil {
+			return errs
+		}
+
+		// A key restricted to a board is only useful if its user may use the board, so the board is
+		// checked like a request of that user.
+		if key.Board != "" {
+			_, err := visibleBoard(tx, key.Board, users[key.User])
+			var httpErr *httpError
+			if errors.As(err, &httpErr) && httpErr.status == http.StatusNotFound {
+				return validation.Errors{{Field: "board", Message: fmt.Sprintf("board %q not found or user %q is not a member", key.Board, key.User)}}
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		key.ID_key, err = ids.Unique("key", func(id string) (bool, error) {
+			_, err := tx.APIKey(id)
+			if errors.Is(err, storage.ErrNotFound) {
+				return false, nil
+			}
+			return err == nil, err
+		})
+		if err != nil {
+			return err
+		}
+		token, err = newAPIKeyToken(key.ID_key)
+		if err != nil {
+			return err
+		}
+		key.KeyHash = hashToken(token)
+		key.Prefix = token[:len(apiKeyPrefix)+len(key.ID_key)+5]
+		return tx.PutAPIKey(key)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing API keys")
+		return
+	}
+
+	w.Header().Set("Location", "/api-keys/"+key.ID_key)
+	writeJSON(w, http.StatusCreated, struct {
+		apiKeyView
+		Key string `json:"key"`
+	}{viewOfKey(key), token})
+}
+
+// The `revokeAPIKey` function handles `DELETE /api-keys/{id}`. The key is deleted, so every further
+// request made with it is rejected, and the response is 204 No Content.
+func revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	err := store.Update(func(tx storage.Tx) error {
+		err := tx.DeleteAPIKey(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return &httpError{http.StatusNotFound, "not_found", fmt.Sprintf("API key %q not found", id)}
+		}
+		return err
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing API keys")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The `authenticateKey` function returns the API key `token` and the user it acts as. An unknown,
+// revoked or expired key, or one whose user no longer exists, results in a 401 error. The time of the
+// use is recorded on the key, at most once per `lastUsedResolution`.
+func authenticateKey(token string) (*structures.User, *structures.APIKey, error) {
+	unauthorized := &httpError{http.StatusUnauthorized, "unauthorized", "a valid API key is required"}
+	id, _, ok := strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), "_")
+	if !ok {
+		return nil, nil, unauthorized
+	}
+
+	now := time.Now().UTC()
+	var user *structures.User
+	var key *structures.APIKey
+	err := store.View(func(tx storage.Tx) error {
+		var err error
+		key, err = tx.APIKey(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return unauthorized
+		}
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(token))) != 1 {
+			return unauthorized
+		}
+		if !key.Expires.IsZero() && now.After(key.Expires) {
+			return &httpError{http.StatusUnauthorized, "unauthorized", "the API key has expired"}
+		}
+
+		user, err = tx.User(key.User)
+		if errors.Is(err, storage.ErrNotFound) {
+			return unauthorized
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if now.Sub(key.LastUsed) >= lastUsedResolution {
+		err = store.Update(func(tx storage.Tx) error {
+			current, err := tx.APIKey(id)
+			if errors.Is(err, storage.ErrNotFound) {
+				return unauthorized
+			}
+			if err != nil {
+				return err
+			}
+			current.LastUsed = now
+			return tx.PutAPIKey(current)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, key, nil
+}
+
+// The `checkAPIKey` function reports whether the request `r` to the route `pattern` is allowed for
+// `key`, on top of the role of its user. A read key may only make GET and HEAD requests and cannot
+// open the WebSocket, whose messages move cards. A key restricted to a board may only use the routes
+// of that board; the unscoped board routes belong to the default board. A request that is not
+// allowed fails with a 403 error.
+func checkAPIKey(key *structures.APIKey, r *http.Request, pattern string) error {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		path = method
+	}
+
+	if key.Scope != structures.ScopeWrite {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || strings.HasSuffix(path, "/ws") {
+			return &httpError{http.StatusForbidden, "forbidden",
+				fmt.Sprintf("API key %s has the %s scope and cannot be used for %s", key.ID_key, key.Scope, pattern)}
+		}
+	}
+
+	if key.Board != "" {
+		board := defaultBoardID
+		if strings.HasPrefix(path, "/boards/{board}/") {
+			board, _, _ = strings.Cut(strings.TrimPrefix(r.URL.Path, "/boards/"), "/")
+		}
+		if !boardPatterns[pattern] || board != key.Board {
+			return &httpError{http.StatusForbidden, "forbidden",
+				fmt.Sprintf("API key %s may only be used for the routes of board %q", key.ID_key, key.Board)}
+		}
+	}
+	return nil
+}
+
+// The `newAPIKeyToken` function returns a new random API key for the key `id`.
+func newAPIKeyToken(id string) (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The `apiKeyServer` function returns `requireAuth` around a few routes of the board `b` and of the
+// board `other`, with the access rules and board patterns of `main`, so that API keys can be checked
+// the way the server checks them. Authentication is switched on until the test ends.
+func apiKeyServer(t *testing.T) http.Handler {
+	t.Helper()
+	member, guest := structures.RoleMember, structures.RoleGuest
+	routes := map[string]struct {
+		handler http.HandlerFunc
+		role    structures.Role
+	}{
+		"GET /tasks":                 {tasks, guest},
+		"POST /tasks":                {createTask, member},
+		"PUT /tasks/{id}":            {replaceTask, member},
+		"PATCH /tasks/{id}":          {patchTask, member},
+		"DELETE /tasks/{id}":         {deleteTaskByID, member},
+		"GET /ws":                    {wsHandler, member},
+		"GET /boards/{board}/tasks":  {tasks, guest},
+		"POST /boards/{board}/tasks": {createTask, member},
+	}
+
+	oldPatterns, oldAuth := boardPatterns, authEnabled
+	boardPatterns, authEnabled = map[string]bool{}, true
+	t.Cleanup(func() { boardPatterns, authEnabled = oldPatterns, oldAuth })
+
+	mux := http.NewServeMux()
+	access := map[string]structures.Role{}
+	for pattern, route := range routes {
+		mux.HandleFunc(pattern, inBoard(route.handler))
+		access[pattern] = route.role
+		boardPatterns[pattern] = true
+	}
+	return requireAuth(mux, access)
+}
+
+// The `putUser` function stores `u` in the root store.
+func putUser(t *testing.T, u *structures.User) {
+	t.Helper()
+	if err := store.Update(func(tx storage.Tx) error { return tx.PutUser(u) }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The `newAPIKey` function creates an API key through `POST /api-keys` as `admin` and returns the key
+// and the token, which is only part of this response.
+func newAPIKey(t *testing.T, admin *structures.User, body string) (apiKeyView, string) {
+	t.Helper()
+	w := serveAs(admin, createAPIKey, "POST", "/api-keys", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating the key %s: got %d %s", body, w.Code, w.Body)
+	}
+	var created struct {
+		apiKeyView
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("the key %s does not start with its prefix %s", created.Key, created.Prefix)
+	}
+	return created.apiKeyView, created.Key
+}
+
+// The `callWithKey` function sends a request with the API key `token` to `handler`.
+func callWithKey(handler http.Handler, token, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIKeyScopeAndBoard(t *testing.T) {
+	newTestBoard(t)
+	ada := &structures.User{ID_user: "usr-ada", Name: "Ada", Email: "ada@example.com", Role: structures.RoleAdmin}
+	putUser(t, ada)
+	if err := ensureDefaultBoard(store); err != nil {
+		t.Fatal(err)
+	}
+	err := store.Update(func(tx storage.Tx) error {
+		return tx.PutBoard(&structures.Board{ID_board: "brd-other", Name: "Other", Members: []string{ada.ID_user}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := apiKeyServer(t)
+
+	_, readKey := newAPIKey(t, ada, `{"name": "read"}`)
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{"GET", "/tasks", http.StatusOK},
+		{"POST", "/tasks", http.StatusForbidden},
+		{"PUT", "/tasks/tk1", http.StatusForbidden},
+		{"PATCH", "/tasks/tk1", http.StatusForbidden},
+		{"DELETE", "/tasks/tk1", http.StatusForbidden},
+		{"GET", "/ws?user=Ada", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if w := callWithKey(server, readKey, tt.method, tt.target, taskJSON("Key")); w.Code != tt.want {
+			t.Errorf("read key, %s %s: got %d %s, want %d", tt.method, tt.target, w.Code, w.Body, tt.want)
+		}
+	}
+
+	_, boardKey := newAPIKey(t, ada, `{"name": "default only", "board": "default", "scope": "write"}`)
+	tests = []struct {
+		method, target string
+		want           int
+	}{
+		{"POST", "/tasks", http.StatusCreated},
+		{"GET", "/boards/default/tasks", http.StatusOK},
+		{"GET", "/boards/brd-other/tasks", http.StatusForbidden},
+		{"POST", "/boards/brd-other/tasks", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if w := callWithKey(server, boardKey, tt.method, tt.target, taskJSON("Key")); w.Code != tt.want {
+			t.Errorf("board key, %s %s: got %d %s, want %d", tt.method, tt.target, w.Code, w.Body, tt.want)
+		}
+	}
+
+	// A key for a board its user is no longer a member of finds the board gone.
+	_, otherKey := newAPIKey(t, ada, `{"name": "other", "board": "brd-other"}`)
+	ada.Role = structures.RoleMember
+	putUser(t, ada)
+	err = store.Update(func(tx storage.Tx) error {
+		return tx.PutBoard(&structures.Board{ID_board: "brd-other", Name: "Other", Members: []string{}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := callWithKey(server, otherKey, "GET", "/boards/brd-other/tasks", ""); w.Code != http.StatusNotFound {
+		t.Errorf("key of a removed member: got %d %s, want 404", w.Code, w.Body)
+	}
+}
+
+func TestAPIKeyValidity(t *testing.T) {
+	newTestBoard(t)
+	ada := &structures.User{ID_user: "usr-ada", Name: "Ada", Email: "ada@example.com", Role: structures.RoleAdmin}
+	putUser(t, ada)
+	if err := ensureDefaultBoard(store); err != nil {
+		t.Fatal(err)
+	}
+	server := apiKeyServer(t)
+
+	key, token := newAPIKey(t, ada, `{"name": "script", "expires_at": "`+time.Now().Add(time.Hour).Format(time.RFC3339)+`"}`)
+	if key.LastUsed != nil {
+		t.Errorf("a new key was last used at %v", key.LastUsed)
+	}
+	if w := callWithKey(server, token, "GET", "/tasks", ""); w.Code != http.StatusOK {
+		t.Fatalf("valid key: got %d %s", w.Code, w.Body)
+	}
+
+	// The use is recorded, and the secret part of the key is never listed.
+	w := serveAs(ada, listAPIKeys, "GET", "/api-keys", "")
+	var listed []apiKeyView
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].LastUsed == nil {
+		t.Errorf("got keys %+v, want one with last_used_at", listed)
+	}
+	secret := strings.TrimPrefix(token, key.Prefix)
+	if strings.Contains(w.Body.String(), secret) || strings.Contains(w.Body.String(), hashToken(token)) {
+		t.Errorf("the key list %s contains the key or its hash", w.Body)
+	}
+
+	wrongSecret := token[:len(token)-4] + "AAAA"
+	if wrongSecret == token {
+		wrongSecret = token[:len(token)-4] + "BBBB"
+	}
+	for name, bad := range map[string]string{
+		"no ID separator": apiKeyPrefix + "nounderscore",
+		"unknown ID":      apiKeyPrefix + "key-none_secret",
+		"wrong secret":    wrongSecret,
+		"prefix only":     apiKeyPrefix,
+	} {
+		if w := callWithKey(server, bad, "GET", "/tasks", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: got %d %s, want 401", name, w.Code, w.Body)
+		}
+	}
+
+	err := store.Update(func(tx storage.Tx) error {
+		k, err := tx.APIKey(key.ID_key)
+		if err != nil {
+			return err
+		}
+		k.Expires = time.Now().Add(-time.Minute)
+		return tx.PutAPIKey(k)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := callWithKey(server, token, "GET", "/tasks", ""); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "expired") {
+		t.Errorf("expired key: got %d %s, want 401", w.Code, w.Body)
+	}
+
+	_, token = newAPIKey(t, ada, `{"name": "revoked"}`)
+	id := strings.SplitN(strings.TrimPrefix(token, apiKeyPrefix), "_", 2)[0]
+	if w := serve(revokeAPIKey, "DELETE", "/api-keys/"+id, "", "id", id); w.Code != http.StatusNoContent {
+		t.Fatalf("revoking: got %d %s", w.Code, w.Body)
+	}
+	if w := callWithKey(server, token, "GET", "/tasks", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: got %d %s, want 401", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minibackend/ids"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The `authEnabled`, `sessionTTL` and `defaultRole` variables configure authentication. They are set
+// in `main` from the `-auth`, `-session-ttl` and `-default-role` flags; disabling authentication is
+// meant for local development only.
+var (
+	authEnabled = true
+	sessionTTL  = 7 * 24 * time.Hour
+	defaultRole = structures.RoleGuest
+)
+
+// The `public` constant marks a route in the access table of `main` that can be called without a
+// session, i.e. one needed to get a session in the first place. It is not a role a user can have.
+const public structures.Role = "public"
+
+// The `userView` struct is how a user is shown to clients. It leaves out the password hash.
+type userView struct {
+	ID_user string          `json:"ID_user"`
+	Name    string          `json:"name"`
+	Email   string          `json:"email"`
+	Role    structures.Role `json:"role"`
+	Created time.Time       `json:"created"`
+}
+
+func viewOf(u *structures.User) userView {
+	return userView{ID_user: u.ID_user, Name: u.Name, Email: u.Email, Role: u.Role, Created: u.Created}
+}
+
+// The `credentials` struct is the body of the register and login requests.
+type credentials struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// The `register` function handles `POST /auth/register`. It creates an account from the name, email
+// address and password in the request body and answers with 201 Created and the new user. The
+// password is stored as a bcrypt hash only. The first account of the board becomes its admin; every
+// later one gets the `defaultRole`, guest unless configured otherwise, so anyone who registers can only
+// read until an admin promotes them. Every new account joins the default board.
+func register(w http.ResponseWriter, r *http.Request) {
+	var body credentials
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	// bcrypt is slow on purpose, so the password is hashed before the write transaction starts instead
+	// of while it holds the lock on the store. The account is validated first, so that an invalid one
+	// is rejected without hashing its password, and again in the transaction, because the email address
+	// may have been registered in the meantime.
+	user := &structures.User{Name: body.Name, Email: strings.TrimSpace(body.Email), Created: time.Now().UTC()}
+	err := store.View(func(tx storage.Tx) error {
+		users, err := tx.Users()
+		if err != nil {
+			return err
+		}
+		if errs := validation.User(user, body.Password, users); errs != nil {
+			return errs
+		}
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error reading users")
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+	user.PasswordHash = string(hash)
+
+	err = store.Update(func(tx storage.Tx) error {
+		users, err := tx.Users()
+		if err != nil {
+			return err
+		}
+		if errs := validation.User(user, body.Password, users); errs != nil {
+			return errs
+		}
+
+		user.Role = defaultRole
+		if len(users) == 0 {
+			user.Role = structures.RoleAdmin
+		}
+		user.ID_user, err = ids.Unique("usr", func(id string) (bool, error) {
+			_, ok := users[id]
+			return ok, nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.PutUser(user); err != nil {
+			return err
+		}
+		return joinDefaultBoard(tx, user.ID_user)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing users")
+		return
+	}
+
+	w.Header().Set("Location", "/auth/me")
+	writeJSON(w, http.StatusCreated, viewOf(user))
+}
+
+// The `login` function handles `POST /auth/login`. If the email address and password in the request
+// body belong to an account, a new session is created and its token returned; the token is sent as
+// `Authorization: Bearer <token>` with every further request. Wrong credentials result in 401
+// Unauthorized without saying whether the email address exists.
+func login(w http.ResponseWriter, r *http.Request) {
+	var body credentials
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		http.Error(w, "Error creating session", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC()
+	session := &structures.Session{TokenHash: hashToken(token), Created: now, Expires: now.Add(sessionTTL)}
+
+	// The account is looked up in a read-only transaction and the password compared outside of any
+	// transaction, since bcrypt is slow on purpose and must not hold up the writers of the store.
+	var user *structures.User
+	err = store.View(func(tx storage.Tx) error {
+		users, err := tx.Users()
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if strings.EqualFold(u.Email, strings.TrimSpace(body.Email)) {
+				user = u
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error reading users")
+		return
+	}
+
+	// An unknown email address is checked against a dummy hash, so that the response time does not tell
+	// whether an account exists.
+	hash := dummyPasswordHash
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(body.Password)) != nil || user == nil {
+		writeError(w, http.StatusUnauthorized, "invalid_credentials", "wrong email address or password")
+		return
+	}
+
+	err = store.Update(func(tx storage.Tx) error {
+		// The account may have been deleted or its password changed since it was read above; the
+		// session is only created for the account as it was checked.
+		current, err := tx.User(user.ID_user)
+		if errors.Is(err, storage.ErrNotFound) || err == nil && current.PasswordHash != user.PasswordHash {
+			return &httpError{http.StatusUnauthorized, "invalid_credentials", "wrong email address or password"}
+		}
+		if err != nil {
+			return err
+		}
+		user = current
+
+		// Expired sessions are removed whenever someone logs in, so the session store does not grow
+		// without bound.
+		sessions, err := tx.Sessions()
+		if err != nil {
+			return err
+		}
+		for hash, s := range sessions {
+			if now.After(s.Expires) {
+				if err := tx.DeleteSession(hash); err != nil {
+					return err
+				}
+			}
+		}
+
+		session.User = user.ID_user
+		return tx.PutSession(session)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing sessions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token":      token,
+		"expires_at": session.Expires,
+		"user":       viewOf(user),
+	})
+}
+
+// The `logout` function handles `POST /auth/logout`. It revokes the session of the token the request
+// was made with, so the token cannot be used again, and answers with 204 No Content.
+func logout(w http.ResponseWriter, r *http.Request) {
+	token := requestToken(r)
+	err := store.Update(func(tx storage.Tx) error {
+		err := tx.DeleteSession(hashToken(token))
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing sessions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The `me` function handles `GET /auth/me` and serves the user the request was made by.
+func me(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "not logged in")
+		return
+	}
+	writeJSON(w, http.StatusOK, viewOf(user))
+}
+
+// The `userKey` type is the context key under which `requireAuth` stores the user of a request.
+type userKey struct{}
+
+// The `currentUser` function returns the user the request was made by, or nil if authentication is
+// disabled.
+func currentUser(r *http.Request) *structures.User {
+	user, _ := r.Context().Value(userKey{}).(*structures.User)
+	return user
+}
+
+// The `requireAuth` function wraps `mux` with the authentication and role check. Every request except
+// those to `public` routes needs the token of a valid session or an API key; the user of the session
+// or key is then available to the handlers through `currentUser`. Requests without a valid token get
+// 401 Unauthorized, and requests by a user whose role does not include the one `access` requires for
+// the route, or that the scope of the API key does not allow, get 403 Forbidden with the reason. A
+// route missing from `access` requires the admin role.
+func requireAuth(mux *http.ServeMux, access map[string]structures.Role) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authEnabled {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		_, pattern := mux.Handler(r)
+		required, ok := access[pattern]
+		if !ok {
+			required = structures.RoleAdmin
+		}
+		if required == public {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		user, key, err := authenticate(requestToken(r))
+		if err != nil {
+			var httpErr *httpError
+			if errors.As(err, &httpErr) && httpErr.status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="minibackend"`)
+			}
+			writeStoreError(w, err, "Error reading sessions")
+			return
+		}
+
+		// A request that matches no route has no pattern; it is passed on so the mux can answer with 404
+		// Not Found or 405 Method Not Allowed.
+		if pattern != "" && !user.Role.Includes(required) {
+			role := string(user.Role)
+			if role == "" {
+				role = "none"
+			}
+			writeError(w, http.StatusForbidden, "forbidden",
+				fmt.Sprintf("%s requires the %s role; your role is %s", pattern, required, role))
+			return
+		}
+		if key != nil && pattern != "" {
+			if err := checkAPIKey(key, r, pattern); err != nil {
+				writeStoreError(w, err, err.Error())
+				return
+			}
+		}
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// The `authenticate` function returns the user of the session with the given token or, for an API
+// key, the user the key acts as together with the key. A missing, unknown or expired token results in
+// a 401 error. The lookup reads only the sessions and users, not the tasks and contacts of the board.
+func authenticate(token string) (*structures.User, *structures.APIKey, error) {
+	unauthorized := &httpError{http.StatusUnauthorized, "unauthorized", "a valid session token is required"}
+	if token == "" {
+		return nil, nil, unauthorized
+	}
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return authenticateKey(token)
+	}
+
+	var user *structures.User
+	err := store.View(func(tx storage.Tx) error {
+		session, err := tx.Session(hashToken(token))
+		if errors.Is(err, storage.ErrNotFound) {
+			return unauthorized
+		}
+		if err != nil {
+			return err
+		}
+		if time.Now().After(session.Expires) {
+			return unauthorized
+		}
+
+		user, err = tx.User(session.User)
+		if errors.Is(err, storage.ErrNotFound) {
+			return unauthorized
+		}
+		return err
+	})
+	return user, nil, err
+}
+
+// The `requestToken` function returns the session token or API key of `r`, taken from the
+// `Authorization: Bearer` header or, for EventSource and WebSocket clients that cannot set headers,
+// from the `access_token` query parameter.
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// The `newSessionToken` function returns a new random session token.
+func newSessionToken() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "mbs_" + base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// The `hashToken` function returns the hex-encoded SHA-256 hash under which the session of `token` is
+// stored. Tokens are long random strings, so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// The `dummyPasswordHash` variable is compared against during a login with an unknown email address.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
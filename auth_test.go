@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"minibackend/structures"
+	"net/http"
+	"testing"
+)
+
+// The `TestRegisterAndLogin` test registers two accounts, logs in with right and wrong passwords and
+// checks that the token of the session identifies the account.
+func TestRegisterAndLogin(t *testing.T) {
+	newTestBoard(t)
+	newUser := func(name string) *structures.User {
+		t.Helper()
+		w := serve(register, "POST", "/auth/register", `{"name": "`+name+`", "email": "`+name+`@example.com", "password": "correct horse"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("registering %s: got %d %s", name, w.Code, w.Body)
+		}
+		var user structures.User
+		if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+			t.Fatal(err)
+		}
+		return &user
+	}
+	if ada := newUser("ada"); ada.Role != structures.RoleAdmin {
+		t.Errorf("the first account got the role %s, want admin", ada.Role)
+	}
+	grace := newUser("grace")
+	if grace.Role != structures.RoleGuest {
+		t.Errorf("the second account got the role %s, want guest", grace.Role)
+	}
+	if w := serve(register, "POST", "/auth/register", `{"name": "Grace", "email": "GRACE@example.com", "password": "correct horse"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("registering a taken email address: got %d %s", w.Code, w.Body)
+	}
+
+	for _, body := range []string{
+		`{"email": "grace@example.com", "password": "wrong horse"}`,
+		`{"email": "nobody@example.com", "password": "correct horse"}`,
+	} {
+		if w := serve(login, "POST", "/auth/login", body); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: got %d %s, want 401", body, w.Code, w.Body)
+		}
+	}
+
+	w := serve(login, "POST", "/auth/login", `{"email": " Grace@Example.com ", "password": "correct horse"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logging in: got %d %s", w.Code, w.Body)
+	}
+	var session struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+		t.Fatal(err)
+	}
+	user, _, err := authenticate(session.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID_user != grace.ID_user {
+		t.Errorf("the token belongs to %s, want %s", user.ID_user, grace.ID_user)
+	}
+}
+
+// The `TestRegisteredGuestReadsDefaultBoard` test registers an account after the default board was
+// added to the registry. The new guest has to be able to read the tasks of the board, but not to
+// change them.
+func TestRegisteredGuestReadsDefaultBoard(t *testing.T) {
+	newTestBoard(t)
+	if err := ensureDefaultBoard(store); err != nil {
+		t.Fatal(err)
+	}
+	server := apiKeyServer(t)
+
+	var token string
+	for _, name := range []string{"ada", "grace"} {
+		body := `{"name": "` + name + `", "email": "` + name + `@example.com", "password": "correct horse"}`
+		if w := serve(register, "POST", "/auth/register", body); w.Code != http.StatusCreated {
+			t.Fatalf("registering %s: got %d %s", name, w.Code, w.Body)
+		}
+		w := serve(login, "POST", "/auth/login", body)
+		var session struct{ Token string }
+		if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+			t.Fatal(err)
+		}
+		token = session.Token
+	}
+
+	if w := callWithKey(server, token, "GET", "/tasks", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /tasks as the new guest: got %d %s, want 200", w.Code, w.Body)
+	}
+	if w := callWithKey(server, token, "POST", "/tasks", taskJSON("Nope")); w.Code != http.StatusForbidden {
+		t.Errorf("POST /tasks as the new guest: got %d %s, want 403", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minibackend/collab"
+	"minibackend/events"
+	"minibackend/ids"
+	"minibackend/search"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The `board` struct is an open board: the store holding its tasks, contacts and categories, and the
+// search index, event bus and collaboration hub derived from it. Every board has its own store, so
+// the routes of one board never see the data of another. `writeMu` serializes the writes to the board
+// together with their write hooks, see `update`.
+type board struct {
+	id    string
+	store storage.Store
+	index *search.Index
+	bus   *events.Bus
+	hub   *collab.Hub
+
+	writeMu sync.Mutex
+}
+
+// The `defaultBoardID` constant is the ID of the board kept directly in the data directory, next to
+// the users, sessions and the list of boards. It is served by the unscoped routes such as `/tasks` as
+// well as under `/boards/default/`, so the existing frontend keeps working. Like every other board it
+// is listed in the board registry and only its members and admins may use it.
+const defaultBoardID = "default"
+
+// The `boardConfig` variable is the storage configuration the boards are opened with. It is set in
+// `main`; every board other than the default one lives in its own directory below
+// <data>/boards/<id>, using the same backend.
+var boardConfig storage.Config
+
+// The `openBoards` variable holds the boards opened so far by ID. A board is opened on its first
+// request and stays open for the lifetime of the server.
+var (
+	openBoardsMu sync.Mutex
+	openBoards   = map[string]*board{}
+)
+
+// The `newBoard` function returns the board `id` on the store `s`, builds its search index and starts
+// forwarding its events to the collaboration hub.
+func newBoard(id string, s storage.Store) (*board, error) {
+	b := &board{id: id, store: s, index: search.New(), bus: events.NewBus(eventHistory)}
+	b.hub = collab.NewHub(func(user string, move collab.Message) error {
+		return moveTask(b, user, move)
+	})
+	if err := buildSearchIndex(b); err != nil {
+		return nil, err
+	}
+	go forwardEvents(b)
+	return b, nil
+}
+
+// The `loadBoard` function returns the open board `id`, opening its store on first use. The caller
+// must have checked that the board exists, since the ID becomes part of a file path.
+func loadBoard(id string) (*board, error) {
+	openBoardsMu.Lock()
+	defer openBoardsMu.Unlock()
+	if b, ok := openBoards[id]; ok {
+		return b, nil
+	}
+
+	cfg := boardConfig
+	cfg.DataDir = filepath.Join(boardConfig.DataDir, "boards", id)
+	cfg.SQLitePath = ""
+	if cfg.Backend != "memory" {
+		if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	s, err := storage.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b, err := newBoard(id, s)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	openBoards[id] = b
+	return b, nil
+}
+
+// The `boardKey` type is the context key under which `inBoard` stores the board of a request.
+type boardKey struct{}
+
+// The `boardOf` function returns the board a request is made on: the one named in the path of a
+// `/boards/{board}/...` route, or the default board for the unscoped routes.
+func boardOf(r *http.Request) *board {
+	if b, ok := r.Context().Value(boardKey{}).(*board); ok {
+		return b
+	}
+	openBoardsMu.Lock()
+	defer openBoardsMu.Unlock()
+	return openBoards[defaultBoardID]
+}
+
+// The `path` method returns the URL path of the resource `p` on the board, e.g. for the `Location`
+// header of a created task.
+func (b *board) path(p string) string {
+	if b.id == defaultBoardID {
+		return p
+	}
+	return "/boards/" + b.id + p
+}
+
+// The `inBoard` function wraps the handler of a board route. The board named by the `{board}`
+// wildcard, or the default board for the unscoped and legacy routes, is looked up and opened before
+// `next` runs; a board that does not exist, or that the user is not a member of, results in 404 Not
+// Found, so boards of other teams stay invisible.
+func inBoard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("board")
+		if id == "" {
+			id = defaultBoardID
+		}
+		err := store.View(func(tx storage.Tx) error {
+			_, err := visibleBoard(tx, id, currentUser(r))
+			return err
+		})
+		if err != nil {
+			writeStoreError(w, err, "Error reading boards")
+			return
+		}
+
+		b, err := loadBoard(id)
+		if err != nil {
+			http.Error(w, "Error opening board", http.StatusInternalServerError)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), boardKey{}, b)))
+	}
+}
+
+// The `listBoards` function handles `GET /boards`. It serves the boards the user may see, the
+// default board first and the others in the order in which they were created.
+func listBoards(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	list := []*structures.Board{}
+	err := store.View(func(tx storage.Tx) error {
+		allBoards, err := tx.Boards()
+		if err != nil {
+			return err
+		}
+		for _, b := range allBoards {
+			if canSee(b, user) {
+				list = append(list, b)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].ID_board == defaultBoardID) != (list[j].ID_board == defaultBoardID) {
+			return list[i].ID_board == defaultBoardID
+		}
+		if !list[i].Created.Equal(list[j].Created) {
+			return list[i].Created.Before(list[j].Created)
+		}
+		return list[i].ID_board < list[j].ID_board
+	})
+	writeJSON(w, http.StatusOK, list)
+}
+
+// The `getBoard` function handles `GET /boards/{board}` and serves the board with that ID.
+func getBoard(w http.ResponseWriter, r *http.Request) {
+	var b *structures.Board
+	err := store.View(func(tx storage.Tx) error {
+		var err error
+		b, err = visibleBoard(tx, r.PathValue("board"), currentUser(r))
+		return err
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error reading boards")
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// The `createBoard` function handles `POST /boards`. It creates an empty board with the name and
+// members from the request body and answers with 201 Created and the new board. The user creating the
+// board always becomes one of its members.
+func createBoard(w http.ResponseWriter, r *http.Request) {
+	b := &structures.Board{}
+	if err := json.NewDecoder(r.Body).Decode(b); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	b.Created = time.Now().UTC()
+	if user := currentUser(r); user != nil && !b.HasMember(user.ID_user) {
+		b.Members = append([]string{user.ID_user}, b.Members...)
+	}
+	if b.Members == nil {
+		b.Members = []string{}
+	}
+
+	err := store.Update(func(tx storage.Tx) error {
+		if err := validateBoard(tx, b); err != nil {
+			return err
+		}
+		var err error
+		b.ID_board, err = ids.Unique("brd", func(id string) (bool, error) {
+			_, err := tx.Board(id)
+			if errors.Is(err, storage.ErrNotFound) {
+				return false, nil
+			}
+			return err == nil, err
+		})
+		if err != nil {
+			return err
+		}
+		return tx.PutBoard(b)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing boards")
+		return
+	}
+
+	w.Header().Set("Location", "/boards/"+b.ID_board)
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// The `updateBoard` function handles `PUT /boards/{board}`. The name and members from the request
+// body replace those of the board, which is how members are added and removed. Only members of the
+// board and admins may change it. This includes the default board, so access to the data that existed
+// before there were boards can be restricted as well.
+func updateBoard(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("board")
+
+	var body structures.Board
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	var b *structures.Board
+	err := store.Update(func(tx storage.Tx) error {
+		var err error
+		b, err = visibleBoard(tx, id, currentUser(r))
+		if err != nil {
+			return err
+		}
+		b.Name = body.Name
+		b.Members = body.Members
+		if b.Members == nil {
+			b.Members = []string{}
+		}
+		if err := validateBoard(tx, b); err != nil {
+			return err
+		}
+		return tx.PutBoard(b)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing boards")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, b)
+}
+
+// The `visibleBoard` function returns the board `id` from the list of boards in `tx`. A board that
+// does not exist or that `user` may not see fails with a 404 error.
+func visibleBoard(tx storage.Tx, id string, user *structures.User) (*structures.Board, error) {
+	b, err := tx.Board(id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && !canSee(b, user)) {
+		return nil, &httpError{http.StatusNotFound, "not_found", fmt.Sprintf("board %q not found", id)}
+	}
+	return b, err
+}
+
+// The `canSee` function reports whether `user` may see and use the board `b`: members and admins
+// may, and so may everybody while authentication is disabled and there is no user.
+func canSee(b *structures.Board, user *structures.User) bool {
+	return user == nil || user.Role == structures.RoleAdmin || b.HasMember(user.ID_user)
+}
+
+// The `ensureDefaultBoard` function adds the default board to the board registry of `s` if it is not
+// listed yet, which is the case for data from before there were boards. Every user that exists at that
+// point becomes a member, so nobody loses access; accounts registered later join it through
+// `joinDefaultBoard`.
+func ensureDefaultBoard(s storage.Store) error {
+	return s.Update(func(tx storage.Tx) error {
+		if _, err := tx.Board(defaultBoardID); !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		users, err := tx.Users()
+		if err != nil {
+			return err
+		}
+		members := make([]string, 0, len(users))
+		for id := range users {
+			members = append(members, id)
+		}
+		sort.Strings(members)
+		return tx.PutBoard(&structures.Board{ID_board: defaultBoardID, Name: "Default", Members: members, Created: time.Now().UTC()})
+	})
+}
+
+// The `joinDefaultBoard` function makes the user `id` a member of the default board in `tx`, so that a
+// new account can use the data that existed before there were boards with the rights of its role. A
+// registry without the default board is left alone, since `ensureDefaultBoard` adds every user when it
+// creates the board.
+func joinDefaultBoard(tx storage.Tx, id string) error {
+	b, err := tx.Board(defaultBoardID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if b.HasMember(id) {
+		return nil
+	}
+	b.Members = append(b.Members, id)
+	return tx.PutBoard(b)
+}
+
+// The `validateBoard` function checks `b` against the board rules of the `validation` package, with
+// the members looked up in `tx`.
+func validateBoard(tx storage.Tx, b *structures.Board) error {
+	users, err := tx.Users()
+	if err != nil {
+		return err
+	}
+	if errs := validation.Board(b, users); errs != nil {
+		return errs
+	}
+	return nil
+}
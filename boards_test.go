@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The `serveAs` function is `serve` for a request made by `user`, as `requireAuth` would pass it on.
+func serveAs(user *structures.User, handler http.HandlerFunc, method, target, body string, pathValues ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(pathValues); i += 2 {
+		r.SetPathValue(pathValues[i], pathValues[i+1])
+	}
+	r = r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// The `TestDefaultBoardMembers` test checks that the default board is restricted to its members like
+// any other board: the users that exist when it is first registered become members, later accounts
+// do not, and its member list can be changed.
+func TestDefaultBoardMembers(t *testing.T) {
+	newTestBoard(t)
+	ada := &structures.User{ID_user: "usr-ada", Name: "Ada", Email: "ada@example.com", Role: structures.RoleAdmin}
+	bob := &structures.User{ID_user: "usr-bob", Name: "Bob", Email: "bob@example.com", Role: structures.RoleMember}
+	carol := &structures.User{ID_user: "usr-carol", Name: "Carol", Email: "carol@example.com", Role: structures.RoleMember}
+	putUsers := func(users ...*structures.User) {
+		t.Helper()
+		err := store.Update(func(tx storage.Tx) error {
+			for _, u := range users {
+				if err := tx.PutUser(u); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	putUsers(ada, bob)
+	if err := ensureDefaultBoard(store); err != nil {
+		t.Fatal(err)
+	}
+	putUsers(carol)
+	if err := ensureDefaultBoard(store); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, user *structures.User, want int) {
+		t.Helper()
+		if w := serveAs(user, inBoard(tasks), "GET", "/tasks", ""); w.Code != want {
+			t.Errorf("%s: got %d %s, want %d", name, w.Code, w.Body, want)
+		}
+		if w := serveAs(user, inBoard(tasks), "GET", "/boards/default/tasks", "", "board", defaultBoardID); w.Code != want {
+			t.Errorf("%s under /boards/default: got %d %s, want %d", name, w.Code, w.Body, want)
+		}
+	}
+	check("existing member", bob, http.StatusOK)
+	check("later account", carol, http.StatusNotFound)
+	check("admin", ada, http.StatusOK)
+
+	w := serveAs(ada, updateBoard, "PUT", "/boards/default", `{"name": "Team", "members": ["usr-carol"]}`, "board", defaultBoardID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("changing the members: got %d %s", w.Code, w.Body)
+	}
+	check("removed member", bob, http.StatusNotFound)
+	check("added member", carol, http.StatusOK)
+
+	var boards []structures.Board
+	w = serveAs(carol, listBoards, "GET", "/boards", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &boards); err != nil {
+		t.Fatal(err)
+	}
+	if len(boards) != 1 || boards[0].ID_board != defaultBoardID || boards[0].Name != "Team" {
+		t.Errorf("carol sees the boards %+v, want only the default board", boards)
+	}
+	w = serveAs(bob, listBoards, "GET", "/boards", "")
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("bob sees the boards %s, want none", w.Body)
+	}
+}
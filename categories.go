@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"minibackend/ids"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The function `categories` loads all categories from the store and serves them as a JSON object
+// mapping category ID to name. It handles `GET /categories`.
+func categories(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The categories are read in a read-only transaction. If the store cannot be read, a 500 Internal
+	// Server Error response with the error message is returned.
+	var allCategories map[string]*structures.Category
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		allCategories, err = tx.Categories()
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The frontend expects the plain ID-to-name object that categories.json has always contained, so
+	// the list keeps that shape; the single category routes serve the full `Category`.
+	names := make(map[string]string, len(allCategories))
+	for id, c := range allCategories {
+		names[id] = c.Name
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+// The `getCategory` function handles `GET /categories/{id}` and serves the single category with that
+// ID. An unknown ID results in a structured 404 Not Found error.
+func getCategory(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	id := r.PathValue("id")
+
+	var category *structures.Category
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		category, err = tx.Category(id)
+		return err
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		err = errCategoryNotFound(id)
+	}
+	if err != nil {
+		writeStoreError(w, err, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// The `createCategory` function handles `POST /categories`. It stores the category from the request
+// body under a new server-generated ID and answers with 201 Created, the new category and its
+// location. A name that is already used by another category is rejected with 422.
+func createCategory(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	category := &structures.Category{}
+	if err := json.NewDecoder(r.Body).Decode(category); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	err := b.update(func(tx storage.Tx) error {
+		var err error
+		category.ID_category, err = newCategoryID(tx)
+		if err != nil {
+			return err
+		}
+		if err := validateCategory(tx, category); err != nil {
+			return err
+		}
+		return tx.PutCategory(category)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated categories")
+		return
+	}
+
+	w.Header().Set("Location", b.path("/categories/"+category.ID_category))
+	writeJSON(w, http.StatusCreated, category)
+}
+
+// The `renameCategory` function handles `PUT /categories/{id}`. The name from the request body
+// replaces the name of the category with the ID from the path; an ID in the body is ignored. Tasks
+// refer to the category by ID, so they keep their category without being rewritten.
+func renameCategory(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var category structures.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	category.ID_category = r.PathValue("id")
+
+	err := b.update(func(tx storage.Tx) error {
+		_, err := tx.Category(category.ID_category)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errCategoryNotFound(category.ID_category)
+		}
+		if err != nil {
+			return err
+		}
+		if err := validateCategory(tx, &category); err != nil {
+			return err
+		}
+		return tx.PutCategory(&category)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated categories")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &category)
+}
+
+// The `patchCategory` function handles `PATCH /categories/{id}` with a JSON Merge Patch or a JSON
+// Patch, as described for `patchTask`. The name is the only field a category has, so the result is
+// the same as renaming it with `PUT`.
+func patchCategory(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	id := r.PathValue("id")
+
+	apply := patchFormat(w, r)
+	if apply == nil {
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	var category *structures.Category
+	err = b.update(func(tx storage.Tx) error {
+		current, err := tx.Category(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errCategoryNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+
+		category = &structures.Category{}
+		if err := applyPatch(apply, current, patch, category); err != nil {
+			return err
+		}
+		category.ID_category = id
+		if err := validateCategory(tx, category); err != nil {
+			return err
+		}
+		return tx.PutCategory(category)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated categories")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// The `deleteCategoryByID` function handles `DELETE /categories/{id}` and answers with 204 No
+// Content. A category that is still used by tasks is not deleted: the request fails with 409 Conflict
+// naming those tasks, unless `?reassign=<category>` names another category that the tasks are moved
+// to in the same transaction.
+func deleteCategoryByID(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	err := removeCategory(b, r.PathValue("id"), r.URL.Query().Get("reassign"))
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated categories")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The `removeCategory` function deletes the category with the given ID. The tasks that use it are
+// moved to the category `reassign`, which gives every moved task a new version; if `reassign` is
+// empty and the category is in use, nothing is changed and a 409 error lists the tasks.
+func removeCategory(b *board, id, reassign string) error {
+	return b.update(func(tx storage.Tx) error {
+		if _, err := tx.Category(id); errors.Is(err, storage.ErrNotFound) {
+			return errCategoryNotFound(id)
+		} else if err != nil {
+			return err
+		}
+
+		if reassign != "" {
+			if reassign == id {
+				return &httpError{http.StatusBadRequest, "invalid_reassign", "a category cannot be reassigned to itself"}
+			}
+			if _, err := tx.Category(reassign); errors.Is(err, storage.ErrNotFound) {
+				return &httpError{http.StatusBadRequest, "invalid_reassign", fmt.Sprintf("category %q not found", reassign)}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		allTasks, err := tx.Tasks()
+		if err != nil {
+			return err
+		}
+		var used []string
+		for taskID, t := range allTasks {
+			if t.Category == id {
+				used = append(used, taskID)
+			}
+		}
+		sort.Strings(used)
+
+		if len(used) > 0 && reassign == "" {
+			return &httpError{http.StatusConflict, "category_in_use", fmt.Sprintf(
+				"category %q is used by tasks %s; pass ?reassign=<category> to move them",
+				id, strings.Join(used, ", "))}
+		}
+		for _, taskID := range used {
+			t := allTasks[taskID]
+			t.Category = reassign
+			t.Version++
+			if err := tx.PutTask(t); err != nil {
+				return err
+			}
+		}
+		return tx.DeleteCategory(id)
+	})
+}
+
+// The `errCategoryNotFound` function returns the 404 error for the unknown category `id`.
+func errCategoryNotFound(id string) error {
+	return &httpError{http.StatusNotFound, "not_found", fmt.Sprintf("category %q not found", id)}
+}
+
+// The `newCategoryID` function returns a new category ID that is not used by any category in `tx`.
+func newCategoryID(tx storage.Tx) (string, error) {
+	return ids.Unique("cat", func(id string) (bool, error) {
+		_, err := tx.Category(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+}
+
+// The `validateCategory` function checks `c` against the category rules of the `validation` package,
+// comparing its name with the other categories in `tx`.
+func validateCategory(tx storage.Tx, c *structures.Category) error {
+	categories, err := tx.Categories()
+	if err != nil {
+		return err
+	}
+	if errs := validation.Category(c, categories); errs != nil {
+		return errs
+	}
+	return nil
+}
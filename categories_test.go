@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The `TestPatchCategory` test renames a category with a merge patch and with a JSON Patch.
+func TestPatchCategory(t *testing.T) {
+	newTestBoard(t)
+
+	w := serve(patchCategory, "PATCH", "/categories/"+testCategory, `{"name": "Design"}`, "id", testCategory)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"Design"`) {
+		t.Fatalf("merge patch: got %d %s", w.Code, w.Body)
+	}
+
+	patch := `[{"op": "test", "path": "/name", "value": "Design"}, {"op": "replace", "path": "/name", "value": "Sales"}]`
+	w = serveWith(patchCategory, "PATCH", "/categories/"+testCategory, patch, "application/json-patch+json", "id", testCategory)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"Sales"`) {
+		t.Fatalf("JSON patch: got %d %s", w.Code, w.Body)
+	}
+
+	if w := serve(patchCategory, "PATCH", "/categories/unknown", `{"name": "X"}`, "id", "unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("PATCH of unknown category: got %d, want 404", w.Code)
+	}
+}
+
+// The `TestCategoryNames` test checks that category names are unique regardless of case and
+// surrounding spaces, while a category may keep its own name when it is renamed.
+func TestCategoryNames(t *testing.T) {
+	newTestBoard(t)
+
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{"create", "", `{"name": "Design"}`, http.StatusCreated},
+		{"create with a taken name", "", `{"name": " design "}`, http.StatusUnprocessableEntity},
+		{"create with the test name", "", `{"name": "TEST"}`, http.StatusUnprocessableEntity},
+		{"create without a name", "", `{"name": "  "}`, http.StatusUnprocessableEntity},
+		{"rename to a taken name", testCategory, `{"name": "Design"}`, http.StatusUnprocessableEntity},
+		{"rename to its own name", testCategory, `{"name": "test"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		var w *httptest.ResponseRecorder
+		if tt.id == "" {
+			w = serve(createCategory, "POST", "/categories", tt.body)
+		} else {
+			w = serve(renameCategory, "PUT", "/categories/"+tt.id, tt.body, "id", tt.id)
+		}
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+}
+
+// The `TestDeleteCategory` test deletes a category that is used by tasks: without `?reassign=` and
+// with an invalid target nothing changes, and with a valid target the tasks move to it.
+func TestDeleteCategory(t *testing.T) {
+	b := newTestBoard(t)
+	task := &structures.Task{Title: "Filed", Status: structures.StatusToDo, Prio: structures.PriorityLow,
+		Category: testCategory}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(createCategory, "POST", "/categories", `{"name": "Archive"}`)
+	var archive structures.Category
+	if err := json.Unmarshal(w.Body.Bytes(), &archive); err != nil {
+		t.Fatal(err)
+	}
+
+	del := func(id, query string) *httptest.ResponseRecorder {
+		return serve(deleteCategoryByID, "DELETE", "/categories/"+id+query, "", "id", id)
+	}
+	refused := []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusConflict},
+		{"?reassign=" + testCategory, http.StatusBadRequest},
+		{"?reassign=cat-unknown", http.StatusBadRequest},
+	}
+	for _, tt := range refused {
+		if w := del(testCategory, tt.query); w.Code != tt.want {
+			t.Errorf("DELETE%s: got %d %s, want %d", tt.query, w.Code, w.Body, tt.want)
+		}
+	}
+	if got := readTask(t, b, task.ID_task); got.Category != testCategory || got.Version != 1 {
+		t.Fatalf("a refused deletion changed the task to category %s, version %d",
+			got.Category, got.Version)
+	}
+
+	if w := del(testCategory, "?reassign="+archive.ID_category); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE with reassign: got %d %s", w.Code, w.Body)
+	}
+	if got := readTask(t, b, task.ID_task); got.Category != archive.ID_category || got.Version != 2 {
+		t.Errorf("reassign: got category %s, version %d, want %s and 2",
+			got.Category, got.Version, archive.ID_category)
+	}
+	if w := del(testCategory, ""); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE of a deleted category: got %d, want 404", w.Code)
+	}
+	if w := del(archive.ID_category, ""); w.Code != http.StatusConflict {
+		t.Errorf("DELETE of the new category of the task: got %d, want 409", w.Code)
+	}
+}
@@ -0,0 +1,103 @@
+// The migrate command applies the data migrations of the `migrations` package to a data set, e.g.
+//
+//	go run ./cmd/migrate -data ./data ids
+//
+// Without arguments every migration is applied in order, to the data directory and to every board
+// below <data>/boards. With -dry-run the changes are only listed.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"minibackend/migrations"
+	"minibackend/storage"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var cfg storage.Config
+	flag.StringVar(&cfg.Backend, "store", "json", "storage backend: json or sqlite")
+	flag.StringVar(&cfg.DataDir, "data", "./data", "directory holding the JSON data files")
+	flag.StringVar(&cfg.SQLitePath, "sqlite", "", "SQLite database file (default <data>/minibackend.db)")
+	dryRun := flag.Bool("dry-run", false, "list the changes without writing them")
+	list := flag.Bool("list", false, "list the available migrations and exit")
+	flag.Parse()
+
+	if *list {
+		for _, m := range migrations.All {
+			fmt.Printf("%-12s %s\n", m.Name, m.Description)
+		}
+		return
+	}
+
+	// The migrations to run are taken from the arguments, defaulting to all of them. Unknown names
+	// are rejected before anything is touched.
+	selected := migrations.All
+	if flag.NArg() > 0 {
+		selected = nil
+		for _, name := range flag.Args() {
+			m, ok := migrations.Lookup(name)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown migration %q (see -list)\n", name)
+				os.Exit(2)
+			}
+			selected = append(selected, m)
+		}
+	}
+
+	// The root data directory holds the default board together with the users and the board registry.
+	// Every other board keeps its tasks, contacts and categories in its own directory below
+	// <data>/boards, opened with the same backend as the server does, and is migrated the same way.
+	migrate(cfg, "", selected, *dryRun)
+	dirs, err := os.ReadDir(filepath.Join(cfg.DataDir, "boards"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "Storage Error:", err)
+		os.Exit(1)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		boardCfg := cfg
+		boardCfg.DataDir = filepath.Join(cfg.DataDir, "boards", dir.Name())
+		boardCfg.SQLitePath = ""
+		migrate(boardCfg, "board "+dir.Name()+": ", selected, *dryRun)
+	}
+}
+
+// The `migrate` function applies the `selected` migrations to the store described by `cfg` and prints
+// their changes, each line starting with `prefix`. It exits the program if any of them fails.
+func migrate(cfg storage.Config, prefix string, selected []migrations.Migration, dryRun bool) {
+	store, err := storage.Open(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Storage Error:", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	// Every migration runs in its own transaction, so a failing migration leaves the data exactly as
+	// the previous one left it.
+	for _, m := range selected {
+		var changes []string
+		err := store.Update(func(tx storage.Tx) error {
+			var err error
+			changes, err = m.Run(tx)
+			if err == nil && dryRun {
+				return migrations.ErrDryRun
+			}
+			return err
+		})
+		if err != nil && !errors.Is(err, migrations.ErrDryRun) {
+			fmt.Fprintf(os.Stderr, "%s%s: %v\n", prefix, m.Name, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s%s: %d change(s)\n", prefix, m.Name, len(changes))
+		for _, c := range changes {
+			fmt.Println("  " + c)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package collab implements the collaboration channel of the board: connected clients see who is
+// editing which task, send card moves, and receive every change to the board. The hub does not know
+// about WebSockets; a transport joins a client with `Hub.Join`, feeds it the messages it reads with
+// `Client.Send` and writes out what arrives on `Client.Messages`. Tests and tools can use the same
+// methods to talk to the hub in-process, without a network connection.
+package collab
+
+import (
+	"sort"
+	"sync"
+)
+
+// The message types of the protocol. Clients send "presence" and "move"; the hub sends "welcome" to a
+// new client, "join", "leave" and "presence" about other clients, and "error" for a message it could
+// not handle. Changes to the board are forwarded with their event type, e.g. "task.updated".
+const (
+	TypeWelcome  = "welcome"
+	TypeJoin     = "join"
+	TypeLeave    = "leave"
+	TypePresence = "presence"
+	TypeMove     = "move"
+	TypeError    = "error"
+)
+
+// The `Message` struct is one message in either direction. Which fields are set depends on `Type`:
+// presence messages carry `User`, `TaskID` and `Editing`, moves carry `TaskID`, `Status` and
+// optionally the `Version` the client has seen, and forwarded events carry `ID` and `Data`.
+type Message struct {
+	Type     string     `json:"type"`
+	ID       uint64     `json:"id,omitempty"`
+	User     string     `json:"user,omitempty"`
+	TaskID   string     `json:"task_id,omitempty"`
+	Status   string     `json:"status,omitempty"`
+	Version  int64      `json:"version,omitempty"`
+	Editing  bool       `json:"editing,omitempty"`
+	Presence []Presence `json:"presence,omitempty"`
+	Data     any        `json:"data,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// The `Presence` struct says that `User` is editing the task `TaskID`.
+type Presence struct {
+	User   string `json:"user"`
+	TaskID string `json:"task_id"`
+}
+
+// The `MoveFunc` type applies a card move sent by `user`. The hub does not broadcast the result
+// itself: the change is expected to reach every client through `Broadcast` like any other change.
+type MoveFunc func(user string, move Message) error
+
+// The `clientBuffer` constant is the number of messages a client may fall behind before the hub
+// disconnects it.
+const clientBuffer = 64
+
+// The `Hub` struct connects the clients of the board. A `Hub` is safe for concurrent use.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	move    MoveFunc
+}
+
+// The `Client` struct is one connected client. `User` is the name shown to the other clients.
+type Client struct {
+	User    string
+	hub     *Hub
+	out     chan Message
+	editing string
+	closed  bool
+}
+
+// The `NewHub` function returns a hub that applies card moves with `move`.
+func NewHub(move MoveFunc) *Hub {
+	return &Hub{clients: make(map[*Client]struct{}), move: move}
+}
+
+// The `Join` method connects a new client for `user`. The client first receives a welcome message
+// listing who is editing what, and the other clients are told that `user` joined.
+func (h *Hub) Join(user string) *Client {
+	c := &Client{User: user, hub: h, out: make(chan Message, clientBuffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.out <- Message{Type: TypeWelcome, User: user, Presence: h.presence()}
+	h.broadcast(Message{Type: TypeJoin, User: user}, nil)
+	h.clients[c] = struct{}{}
+	return c
+}
+
+// The `Broadcast` method sends `msg` to every connected client.
+func (h *Hub) Broadcast(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broadcast(msg, nil)
+}
+
+// The `broadcast` method sends `msg` to every client except `except`. A client whose buffer is full
+// is disconnected rather than allowed to hold up the others. The caller must hold `h.mu`.
+func (h *Hub) broadcast(msg Message, except *Client) {
+	for c := range h.clients {
+		if c == except {
+			continue
+		}
+		select {
+		case c.out <- msg:
+		default:
+			h.remove(c)
+		}
+	}
+}
+
+// The `presence` method lists which client is editing which task, ordered by user. The caller must
+// hold `h.mu`.
+func (h *Hub) presence() []Presence {
+	list := []Presence{}
+	for c := range h.clients {
+		if c.editing != "" {
+			list = append(list, Presence{User: c.User, TaskID: c.editing})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].User < list[j].User })
+	return list
+}
+
+// The `remove` method disconnects `c` and tells the other clients that it left. The caller must hold
+// `h.mu`.
+func (h *Hub) remove(c *Client) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(h.clients, c)
+	close(c.out)
+	if c.editing != "" {
+		h.broadcast(Message{Type: TypePresence, User: c.User, TaskID: c.editing, Editing: false}, nil)
+	}
+	h.broadcast(Message{Type: TypeLeave, User: c.User}, nil)
+}
+
+// The `Messages` method returns the channel on which the client receives messages from the hub. It is
+// closed when the client leaves or is disconnected.
+func (c *Client) Messages() <-chan Message {
+	return c.out
+}
+
+// The `Send` method handles a message sent by the client. A presence message is passed on to the
+// other clients, a move is applied with the hub's `MoveFunc`, and anything else, as well as a move
+// that fails, is answered with an error message to this client only.
+func (c *Client) Send(msg Message) {
+	switch msg.Type {
+	case TypePresence:
+		h := c.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if c.closed {
+			return
+		}
+		switch {
+		case msg.Editing:
+			c.editing = msg.TaskID
+		case msg.TaskID == "" || msg.TaskID == c.editing:
+			c.editing = ""
+		}
+		h.broadcast(Message{Type: TypePresence, User: c.User, TaskID: msg.TaskID, Editing: msg.Editing}, c)
+
+	case TypeMove:
+		if err := c.hub.move(c.User, msg); err != nil {
+			c.Reply(Message{Type: TypeError, TaskID: msg.TaskID, Error: err.Error()})
+		}
+
+	default:
+		c.Reply(Message{Type: TypeError, Error: "unknown message type " + msg.Type})
+	}
+}
+
+// The `Reply` method sends `msg` to this client only, e.g. to report a message it could not handle.
+func (c *Client) Reply(msg Message) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.out <- msg:
+	default:
+		h.remove(c)
+	}
+}
+
+// The `Leave` method disconnects the client. It is safe to call more than once.
+func (c *Client) Leave() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.remove(c)
+}
@@ -0,0 +1,120 @@
+package collab
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The `next` function returns the next message of `c`, failing the test if none arrives in time.
+func next(t *testing.T, c *Client) Message {
+	t.Helper()
+	select {
+	case msg, ok := <-c.Messages():
+		if !ok {
+			t.Fatalf("%s was disconnected", c.User)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("%s received no message", c.User)
+	}
+	return Message{}
+}
+
+// The `expect` function fails the test unless the next message of `c` is `want`.
+func expect(t *testing.T, c *Client, want Message) {
+	t.Helper()
+	if got := next(t, c); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %+v, want %+v", c.User, got, want)
+	}
+}
+
+func TestJoinAndPresence(t *testing.T) {
+	h := NewHub(nil)
+	ada := h.Join("ada")
+	expect(t, ada, Message{Type: TypeWelcome, User: "ada", Presence: []Presence{}})
+
+	ada.Send(Message{Type: TypePresence, TaskID: "tk1", Editing: true})
+	grace := h.Join("grace")
+	expect(t, grace, Message{Type: TypeWelcome, User: "grace", Presence: []Presence{{User: "ada", TaskID: "tk1"}}})
+	expect(t, ada, Message{Type: TypeJoin, User: "grace"})
+
+	// A presence message goes to the other clients only.
+	grace.Send(Message{Type: TypePresence, TaskID: "tk2", Editing: true})
+	expect(t, ada, Message{Type: TypePresence, User: "grace", TaskID: "tk2", Editing: true})
+	select {
+	case msg := <-grace.Messages():
+		t.Errorf("grace received her own presence: %+v", msg)
+	default:
+	}
+
+	// Leaving ends the editing of the client and tells the others.
+	grace.Leave()
+	grace.Leave()
+	expect(t, ada, Message{Type: TypePresence, User: "grace", TaskID: "tk2"})
+	expect(t, ada, Message{Type: TypeLeave, User: "grace"})
+	if _, ok := <-grace.Messages(); ok {
+		t.Error("the messages of a client that left are not closed")
+	}
+
+	ada.Send(Message{Type: "dance"})
+	expect(t, ada, Message{Type: TypeError, Error: "unknown message type dance"})
+}
+
+func TestMove(t *testing.T) {
+	var moves []Message
+	h := NewHub(func(user string, move Message) error {
+		if move.Status == "Nowhere" {
+			return errors.New("unknown column")
+		}
+		moves = append(moves, Message{Type: move.Type, User: user, TaskID: move.TaskID, Status: move.Status})
+		return nil
+	})
+	ada := h.Join("ada")
+	next(t, ada)
+
+	ada.Send(Message{Type: TypeMove, TaskID: "tk1", Status: "Done"})
+	if want := []Message{{Type: TypeMove, User: "ada", TaskID: "tk1", Status: "Done"}}; !reflect.DeepEqual(moves, want) {
+		t.Errorf("got moves %+v, want %+v", moves, want)
+	}
+
+	ada.Send(Message{Type: TypeMove, TaskID: "tk1", Status: "Nowhere"})
+	expect(t, ada, Message{Type: TypeError, TaskID: "tk1", Error: "unknown column"})
+
+	h.Broadcast(Message{Type: "task.updated", ID: 7})
+	expect(t, ada, Message{Type: "task.updated", ID: 7})
+}
+
+// The `TestSlowClientIsDropped` test fills the buffer of a client that never reads. The hub must
+// disconnect it instead of blocking, and keep serving the other clients.
+func TestSlowClientIsDropped(t *testing.T) {
+	h := NewHub(nil)
+	slow := h.Join("slow")
+	fast := h.Join("fast")
+	next(t, fast)
+
+	// The welcome and the join of the fast client already wait in the buffer of the slow one, so the
+	// broadcast after the next clientBuffer-2 ones no longer fits. The hub may deliver it to the fast
+	// client before or after announcing that the slow one left.
+	for i := 0; i < clientBuffer-2; i++ {
+		h.Broadcast(Message{Type: "task.updated", ID: uint64(i + 1)})
+		next(t, fast)
+	}
+	h.Broadcast(Message{Type: "task.updated", ID: clientBuffer})
+	got := []Message{next(t, fast), next(t, fast)}
+	leave := Message{Type: TypeLeave, User: "slow"}
+	if !reflect.DeepEqual(got[0], leave) && !reflect.DeepEqual(got[1], leave) {
+		t.Errorf("fast: got %+v, want the slow client to leave", got)
+	}
+
+	received := 0
+	for range slow.Messages() {
+		received++
+	}
+	if received != clientBuffer {
+		t.Errorf("the slow client received %d messages before it was dropped, want %d", received, clientBuffer)
+	}
+	slow.Send(Message{Type: TypePresence, TaskID: "tk1", Editing: true})
+	slow.Reply(Message{Type: TypeError})
+}
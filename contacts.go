@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"minibackend/ids"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The `contacts` function loads all contacts from the store and serves them as a JSON object keyed by
+// contact ID. It handles `GET /contacts`.
+func contacts(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The contacts are read in a read-only transaction. If the store cannot be read, a 500 Internal
+	// Server Error response with the error message is returned.
+	var allContacts map[string]*structures.Contact
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		allContacts, err = tx.Contacts()
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, allContacts)
+}
+
+// The `getContact` function handles `GET /contacts/{id}` and serves the single contact with that ID.
+// An unknown ID results in a structured 404 Not Found error.
+func getContact(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	id := r.PathValue("id")
+
+	var contact *structures.Contact
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		contact, err = tx.Contact(id)
+		return err
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		err = errContactNotFound(id)
+	}
+	if err != nil {
+		writeStoreError(w, err, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag(contact.Version))
+	writeJSON(w, http.StatusOK, contact)
+}
+
+// The `createContact` function handles `POST /contacts`. It stores the contact from the request body
+// under a new server-generated ID and answers with 201 Created, the new contact and its location.
+func createContact(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The request body is decoded into a new contact. Malformed JSON results in a structured 400 Bad
+	// Request response with the decoding error.
+	newContact := &structures.Contact{}
+	if err := json.NewDecoder(r.Body).Decode(newContact); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	if err := insertContact(b, newContact); err != nil {
+		writeStoreError(w, err, "Error writing updated contacts")
+		return
+	}
+
+	w.Header().Set("Location", b.path("/contacts/"+newContact.ID_contact))
+	w.Header().Set("ETag", etag(newContact.Version))
+	writeJSON(w, http.StatusCreated, newContact)
+}
+
+// The `replaceContact` function handles `PUT /contacts/{id}`. The contact from the request body
+// replaces the stored contact with the ID from the path; an ID in the body is ignored. As for tasks,
+// an unknown ID results in 404 Not Found unless `?upsert=true` asks for the contact to be created, and
+// an `If-Match` header makes the replacement conditional on the current ETag of the contact.
+func replaceContact(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var contact structures.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	contact.ID_contact = r.PathValue("id")
+
+	created, err := saveContact(b, &contact, r.URL.Query().Get("upsert") == "true", r.Header.Get("If-Match"))
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated contacts")
+		return
+	}
+
+	w.Header().Set("ETag", etag(contact.Version))
+	if created {
+		w.Header().Set("Location", b.path("/contacts/"+contact.ID_contact))
+		writeJSON(w, http.StatusCreated, &contact)
+		return
+	}
+	writeJSON(w, http.StatusOK, &contact)
+}
+
+// The `patchContact` function handles `PATCH /contacts/{id}`. Like `patchTask`, it accepts a JSON
+// Merge Patch or a JSON Patch and only changes the fields contained in the patch, so editing the phone
+// number does not overwrite a concurrent change of the email address. An `If-Match` header makes the
+// patch conditional on the current ETag of the contact.
+func patchContact(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	id := r.PathValue("id")
+
+	apply := patchFormat(w, r)
+	if apply == nil {
+		return
+	}
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	var contact *structures.Contact
+	err = b.update(func(tx storage.Tx) error {
+		current, err := tx.Contact(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errContactNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r.Header.Get("If-Match"), true, current.Version); err != nil {
+			return err
+		}
+
+		contact = &structures.Contact{}
+		if err := applyPatch(apply, current, patch, contact); err != nil {
+			return err
+		}
+		contact.ID_contact = id
+		contact.Version = current.Version + 1
+		if errs := validation.Contact(contact); errs != nil {
+			return errs
+		}
+		return tx.PutContact(contact)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated contacts")
+		return
+	}
+
+	w.Header().Set("ETag", etag(contact.Version))
+	writeJSON(w, http.StatusOK, contact)
+}
+
+// The `deleteContactByID` function handles `DELETE /contacts/{id}` and answers with 204 No Content.
+// An `If-Match` header makes the deletion conditional on the current ETag of the contact. What happens
+// to the tasks assigned to the contact is decided by the server's `-contact-delete-policy`, which a
+// request can override with `?policy=reject|unassign|reassign` and `?reassign=<contact>`.
+func deleteContactByID(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	policy, err := contactPolicyFromQuery(r)
+	if err != nil {
+		writeStoreError(w, err, err.Error())
+		return
+	}
+	if err := removeContactByID(b, r.PathValue("id"), r.Header.Get("If-Match"), policy); err != nil {
+		writeStoreError(w, err, "Error writing updated contacts")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The `insertContact` function validates `c` and stores it as a new contact under a server-generated
+// ID that is not yet used in the store, overriding any ID sent by the client. The contact starts at
+// version 1.
+func insertContact(b *board, c *structures.Contact) error {
+	c.Version = 1
+	if errs := validation.Contact(c); errs != nil {
+		return errs
+	}
+
+	return b.update(func(tx storage.Tx) error {
+		var err error
+		c.ID_contact, err = newContactID(tx)
+		if err != nil {
+			return err
+		}
+		return tx.PutContact(c)
+	})
+}
+
+// The `saveContact` function validates `c` and stores it under its `ID_contact`, replacing the
+// existing contact with that ID. Like `saveTask`, it fails with a 404 error for an unknown ID unless
+// `upsert` is set, always rejects an empty ID, checks a non-empty `ifMatch` against the ETag of the
+// stored contact and sets the version of the contact itself.
+func saveContact(b *board, c *structures.Contact, upsert bool, ifMatch string) (created bool, err error) {
+	if c.ID_contact == "" {
+		return false, &httpError{http.StatusBadRequest, "missing_id", "Contact ID not provided"}
+	}
+	if errs := validation.Contact(c); errs != nil {
+		return false, errs
+	}
+
+	err = b.update(func(tx storage.Tx) error {
+		current, err := tx.Contact(c.ID_contact)
+		switch {
+		case errors.Is(err, storage.ErrNotFound) && upsert:
+			created = true
+		case errors.Is(err, storage.ErrNotFound):
+			return errContactNotFound(c.ID_contact)
+		case err != nil:
+			return err
+		}
+
+		if created {
+			err = checkIfMatch(ifMatch, false, 0)
+			c.Version = 1
+		} else {
+			err = checkIfMatch(ifMatch, true, current.Version)
+			c.Version = current.Version + 1
+		}
+		if err != nil {
+			return err
+		}
+		return tx.PutContact(c)
+	})
+	return created, err
+}
+
+// The `removeContactByID` function deletes the contact with the given ID. Deleting an ID that does
+// not exist fails with a 404 error, and a non-empty `ifMatch` has to match the ETag of the contact.
+// The tasks assigned to the contact are handled according to `policy` in the same transaction, so the
+// contact and task files never disagree.
+func removeContactByID(b *board, id, ifMatch string, policy contactPolicy) error {
+	return b.update(func(tx storage.Tx) error {
+		current, err := tx.Contact(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errContactNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(ifMatch, true, current.Version); err != nil {
+			return err
+		}
+		if err := releaseAssignedTasks(tx, id, policy); err != nil {
+			return err
+		}
+		return tx.DeleteContact(id)
+	})
+}
+
+// The `contactPolicy` struct says what happens to the tasks of a contact that is deleted. `Action` is
+// one of the `policy…` constants; `ReassignTo` is the contact that takes over the tasks when
+// `Action` is `policyReassign`.
+type contactPolicy struct {
+	Action     string
+	ReassignTo string
+}
+
+// The actions of a `contactPolicy`. Rejecting is the default because it never changes a task behind
+// the user's back.
+const (
+	policyReject   = "reject"
+	policyUnassign = "unassign"
+	policyReassign = "reassign"
+)
+
+// The `defaultContactPolicy` variable holds the policy used when a request does not choose one. It is
+// set in `main` from the `-contact-delete-policy` and `-contact-reassign-to` flags.
+var defaultContactPolicy = contactPolicy{Action: policyReject}
+
+// The `contactPolicyFromQuery` function returns the policy requested by the `policy` and `reassign`
+// query parameters of `r`, falling back to `defaultContactPolicy`. A `reassign` target on its own
+// implies the reassign action.
+func contactPolicyFromQuery(r *http.Request) (contactPolicy, error) {
+	policy := defaultContactPolicy
+	query := r.URL.Query()
+	if target := query.Get("reassign"); target != "" {
+		policy = contactPolicy{Action: policyReassign, ReassignTo: target}
+	}
+	if action := query.Get("policy"); action != "" {
+		policy.Action = action
+	}
+	if err := policy.check(); err != nil {
+		return policy, &httpError{http.StatusBadRequest, "invalid_policy", err.Error()}
+	}
+	return policy, nil
+}
+
+// The `check` method reports whether the policy names a known action and, for reassignment, a target.
+func (p contactPolicy) check() error {
+	switch p.Action {
+	case policyReject, policyUnassign:
+		return nil
+	case policyReassign:
+		if p.ReassignTo == "" {
+			return errors.New("the reassign policy needs a contact to reassign the tasks to")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown contact delete policy %q, expected reject, unassign or reassign", p.Action)
+}
+
+// The `releaseAssignedTasks` function applies `policy` to the tasks in `tx` that are assigned to
+// the contact `id`. Rejecting fails with a 409 error naming the tasks, unassigning removes the
+// contact from their assignees, and reassigning hands them to `policy.ReassignTo`. Every changed
+// task gets a new version.
+func releaseAssignedTasks(tx storage.Tx, id string, policy contactPolicy) error {
+	allTasks, err := tx.Tasks()
+	if err != nil {
+		return err
+	}
+	var assigned []string
+	for taskID, t := range allTasks {
+		if t.HasAssignee(id) {
+			assigned = append(assigned, taskID)
+		}
+	}
+	if len(assigned) == 0 {
+		return nil
+	}
+	sort.Strings(assigned)
+
+	var newAssignee string
+	switch policy.Action {
+	case policyReject:
+		return &httpError{http.StatusConflict, "contact_in_use", fmt.Sprintf(
+			"contact %q is assigned to tasks %s; pass ?policy=unassign or ?reassign=<contact> to release them",
+			id, strings.Join(assigned, ", "))}
+	case policyReassign:
+		if policy.ReassignTo == id {
+			return &httpError{http.StatusBadRequest, "invalid_policy", "a contact cannot be reassigned to itself"}
+		}
+		if _, err := tx.Contact(policy.ReassignTo); errors.Is(err, storage.ErrNotFound) {
+			return &httpError{http.StatusBadRequest, "invalid_policy", fmt.Sprintf("contact %q not found", policy.ReassignTo)}
+		} else if err != nil {
+			return err
+		}
+		newAssignee = policy.ReassignTo
+	}
+
+	// The contact is dropped from the assignees of every task. When reassigning, the new contact takes
+	// its place unless it is already assigned to the task.
+	for _, taskID := range assigned {
+		t := allTasks[taskID]
+		assignees := make([]string, 0, len(t.Assignees))
+		for _, a := range t.Assignees {
+			switch {
+			case a != id:
+				assignees = append(assignees, a)
+			case newAssignee != "" && !t.HasAssignee(newAssignee):
+				assignees = append(assignees, newAssignee)
+			}
+		}
+		t.Assignees = assignees
+		t.Version++
+		if err := tx.PutTask(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The `errContactNotFound` function returns the 404 error for the unknown contact `id`.
+func errContactNotFound(id string) error {
+	return &httpError{http.StatusNotFound, "not_found", fmt.Sprintf("contact %q not found", id)}
+}
+
+// The `newContactID` function returns a new contact ID that is not used by any contact in `tx`.
+func newContactID(tx storage.Tx) (string, error) {
+	return ids.Unique("cont", func(id string) (bool, error) {
+		_, err := tx.Contact(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+}
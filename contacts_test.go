@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The `TestPatchContact` test changes the phone number of a contact with a merge patch and checks that
+// the other fields and the version are kept up to date, and that an invalid result is rejected.
+func TestPatchContact(t *testing.T) {
+	b := newTestBoard(t)
+	c := &structures.Contact{First_Name: "Ada", Last_Name: "Lovelace", Email: "ada@example.com"}
+	if err := insertContact(b, c); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(patchContact, "PATCH", "/contacts/"+c.ID_contact, `{"phone": "+49 30 1234567"}`, "id", c.ID_contact)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: got %d %s", w.Code, w.Body)
+	}
+	var patched structures.Contact
+	if err := json.Unmarshal(w.Body.Bytes(), &patched); err != nil {
+		t.Fatal(err)
+	}
+	if patched.Phone != "+49 30 1234567" || patched.Email != c.Email || patched.Last_Name != c.Last_Name {
+		t.Errorf("PATCH: got %+v", patched)
+	}
+	if patched.Version != 2 || w.Header().Get("ETag") != etag(2) {
+		t.Errorf("PATCH: got version %d and ETag %s, want 2", patched.Version, w.Header().Get("ETag"))
+	}
+
+	if w := serve(patchContact, "PATCH", "/contacts/"+c.ID_contact, `{"email": "not an address"}`, "id", c.ID_contact); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("PATCH with invalid email: got %d, want 422", w.Code)
+	}
+	if w := serve(patchContact, "PATCH", "/contacts/unknown", `{"phone": "12345"}`, "id", "unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("PATCH of unknown contact: got %d, want 404", w.Code)
+	}
+}
+
+// The `TestReplaceContactIDs` test is the contact counterpart of `TestReplaceTaskIDs`.
+func TestReplaceContactIDs(t *testing.T) {
+	b := newTestBoard(t)
+	body := contactJSON("Upserted")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		id      string
+		want    int
+	}{
+		{"PUT without ID", replaceContact, "PUT", "/contacts/", "", http.StatusBadRequest},
+		{"PUT unknown ID", replaceContact, "PUT", "/contacts/cont-new", "cont-new", http.StatusNotFound},
+		{"DELETE unknown ID", deleteContactByID, "DELETE", "/contacts/cont-new", "cont-new", http.StatusNotFound},
+		{"PUT upsert", replaceContact, "PUT", "/contacts/cont-new?upsert=true", "cont-new", http.StatusCreated},
+		{"PUT existing ID", replaceContact, "PUT", "/contacts/cont-new", "cont-new", http.StatusOK},
+		{"DELETE existing ID", deleteContactByID, "DELETE", "/contacts/cont-new", "cont-new", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		w := serve(tt.handler, tt.method, tt.target, body, "id", tt.id)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+
+	if _, contacts := countEntities(t, b); contacts != 0 {
+		t.Errorf("got %d contacts, want none", contacts)
+	}
+}
+
+// The `TestDeleteContactPolicies` test deletes contacts that are assigned to tasks under every delete
+// policy, both chosen by the request and taken from the `-contact-delete-policy` default.
+func TestDeleteContactPolicies(t *testing.T) {
+	b := newTestBoard(t)
+	contact := map[string]string{}
+	for _, name := range []string{"Ada", "Grace", "Linus", "Idle"} {
+		c := &structures.Contact{First_Name: name, Email: strings.ToLower(name) + "@example.com"}
+		if err := insertContact(b, c); err != nil {
+			t.Fatal(err)
+		}
+		contact[name] = c.ID_contact
+	}
+	task := map[string]string{}
+	for title, assignees := range map[string][]string{
+		"Solo":  {contact["Ada"]},
+		"Pair":  {contact["Ada"], contact["Grace"]},
+		"Other": {contact["Linus"]},
+	} {
+		tk := &structures.Task{Title: title, Status: structures.StatusToDo, Prio: structures.PriorityLow,
+			Category: testCategory, Assignees: assignees}
+		if err := insertTask(b, tk); err != nil {
+			t.Fatal(err)
+		}
+		task[title] = tk.ID_task
+	}
+
+	del := func(name, query string) *httptest.ResponseRecorder {
+		id := contact[name]
+		return serve(deleteContactByID, "DELETE", "/contacts/"+id+query, "", "id", id)
+	}
+	refused := []struct {
+		name, query string
+		want        int
+	}{
+		{"Ada", "", http.StatusConflict},
+		{"Ada", "?policy=archive", http.StatusBadRequest},
+		{"Ada", "?policy=reassign", http.StatusBadRequest},
+		{"Ada", "?reassign=ct-unknown", http.StatusBadRequest},
+		{"Ada", "?reassign=" + contact["Ada"], http.StatusBadRequest},
+	}
+	for _, tt := range refused {
+		if w := del(tt.name, tt.query); w.Code != tt.want {
+			t.Errorf("DELETE %s%s: got %d %s, want %d", tt.name, tt.query, w.Code, w.Body, tt.want)
+		}
+	}
+	if _, contacts := countEntities(t, b); contacts != 4 {
+		t.Fatalf("a refused deletion removed a contact, %d are left", contacts)
+	}
+	if w := del("Idle", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE of a contact without tasks: got %d %s, want 204", w.Code, w.Body)
+	}
+
+	// Reassigning hands both tasks of Ada to Grace, who must not end up twice on the task she already
+	// shares with Ada.
+	if w := del("Ada", "?reassign="+contact["Grace"]); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE with reassign: got %d %s", w.Code, w.Body)
+	}
+	for _, title := range []string{"Solo", "Pair"} {
+		got := readTask(t, b, task[title])
+		if !reflect.DeepEqual(got.Assignees, []string{contact["Grace"]}) || got.Version != 2 {
+			t.Errorf("reassign: task %s has assignees %v and version %d, want [%s] and 2",
+				title, got.Assignees, got.Version, contact["Grace"])
+		}
+	}
+
+	// With the unassign default, Grace is simply removed, while a request can still insist on the
+	// reject policy.
+	oldPolicy := defaultContactPolicy
+	defaultContactPolicy = contactPolicy{Action: policyUnassign}
+	t.Cleanup(func() { defaultContactPolicy = oldPolicy })
+
+	if w := del("Linus", "?policy=reject"); w.Code != http.StatusConflict {
+		t.Errorf("DELETE with policy=reject: got %d %s, want 409", w.Code, w.Body)
+	}
+	if w := del("Grace", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE with the unassign default: got %d %s", w.Code, w.Body)
+	}
+	for _, title := range []string{"Solo", "Pair"} {
+		if got := readTask(t, b, task[title]); len(got.Assignees) != 0 || got.Version != 3 {
+			t.Errorf("unassign: task %s has assignees %v and version %d, want none and 3",
+				title, got.Assignees, got.Version)
+		}
+	}
+}
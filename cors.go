@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The `corsConfig` struct configures the CORS (Cross-Origin Resource Sharing) headers, which allow the
+// Kanban frontend to call the API from another origin. `AllowedOrigins` lists the origins whose
+// requests browsers may read, e.g. "https://join.denniscodeworld.de", or "*" for any origin.
+// `AllowedMethods` and `AllowedHeaders` are announced to preflight requests, `AllowCredentials` lets
+// browsers send cookies and HTTP authentication, which requires a list of origins, and `MaxAge` is how
+// long a browser may cache the answer to a preflight request.
+type corsConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// The `corsSettings` variable holds the CORS configuration. It is set in `main` from the `-cors-*`
+// flags; the defaults allow the production frontend to use every route.
+var corsSettings = corsConfig{
+	AllowedOrigins: []string{"https://join.denniscodeworld.de"},
+	AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+	AllowedHeaders: []string{"Authorization", "Content-Type", "If-Match", "Last-Event-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+// The `corsExposedHeaders` variable lists the response headers that the frontend may read besides the
+// ones browsers always expose.
+var corsExposedHeaders = []string{"ETag", "Location", "X-Total-Count", "Deprecation", "Link", "WWW-Authenticate"}
+
+// The `withCORS` function wraps `next` with the CORS headers of `cfg`. It is the outermost handler of
+// the server, so every response, including 401 and 403 errors, carries the headers. A preflight
+// request, an OPTIONS request with an `Access-Control-Request-Method` header, is answered here with
+// 204 No Content and never reaches `next`, since browsers send it without the token. Requests from an
+// origin that is not allowed get no CORS headers, so the browser refuses to pass the response on.
+func withCORS(next http.Handler, cfg corsConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		// The answer depends on the Origin header unless every origin gets the same "*", so caches must
+		// keep the responses for different origins apart.
+		h := w.Header()
+		if !cfg.allowsAnyOrigin() {
+			h.Add("Vary", "Origin")
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin != "" && cfg.allowsOrigin(origin) {
+			// Credentials are only ever allowed for a listed origin. Echoing the origin of any site
+			// together with them would let every website act with the cookies of the user, so the
+			// wildcard is sent without them even if `check` was skipped.
+			if cfg.allowsAnyOrigin() {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if preflight {
+				h.Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+				h.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+				if cfg.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+			} else {
+				h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			}
+		}
+
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The `check` method reports whether the configuration is safe to use. Credentials cannot be combined
+// with "*", because browsers would then let any website send requests in the name of the user; the
+// origins of the frontend have to be listed instead.
+func (c corsConfig) check() error {
+	if c.AllowCredentials && c.allowsAnyOrigin() {
+		return errors.New("-cors-credentials needs an explicit list of origins in -cors-origins, not *")
+	}
+	return nil
+}
+
+// The `allowsOrigin` method reports whether requests from `origin` may be read by the browser.
+// Origins are compared case-insensitively, as browsers send the scheme and host in lower case.
+func (c corsConfig) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// The `allowsAnyOrigin` method reports whether the configuration allows every origin.
+func (c corsConfig) allowsAnyOrigin() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// The `splitList` function splits a comma-separated flag value into its trimmed, non-empty items.
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
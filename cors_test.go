@@ -0,0 +1,126 @@
+package main
+
+import (
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The `TestCORSCredentials` test checks that credentials are refused together with the "*" origin
+// when the flags are checked, and are never sent for it even if the check is skipped.
+func TestCORSCredentials(t *testing.T) {
+	wildcard := corsConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if wildcard.check() == nil {
+		t.Error("credentials with the * origin passed the check")
+	}
+	listed := corsConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}
+	if err := listed.check(); err != nil {
+		t.Errorf("credentials with a listed origin: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name        string
+		cfg         corsConfig
+		origin      string
+		wantOrigin  string
+		credentials string
+	}{
+		{"wildcard", wildcard, "https://evil.example.com", "*", ""},
+		{"listed origin", listed, "https://app.example.com", "https://app.example.com", "true"},
+		{"other origin", listed, "https://evil.example.com", "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/tasks", nil)
+		r.Header.Set("Origin", tt.origin)
+		w := httptest.NewRecorder()
+		withCORS(ok, tt.cfg).ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+			t.Errorf("%s: got Access-Control-Allow-Origin %q, want %q", tt.name, got, tt.wantOrigin)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+			t.Errorf("%s: got Access-Control-Allow-Credentials %q, want %q", tt.name, got, tt.credentials)
+		}
+	}
+}
+
+// The `TestCORSPreflight` test sends a preflight request through the authentication middleware. It
+// has to be answered with 204 and the announced methods, headers and max age without a token, and
+// must not reach the route.
+func TestCORSPreflight(t *testing.T) {
+	newTestBoard(t)
+	authEnabled = true
+
+	reached := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) { reached = true })
+	access := map[string]structures.Role{"GET /tasks": structures.RoleMember}
+	handler := withCORS(requireAuth(mux, access), corsSettings)
+
+	r := httptest.NewRequest("OPTIONS", "/tasks", nil)
+	r.Header.Set("Origin", "https://join.denniscodeworld.de")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	r.Header.Set("Access-Control-Request-Headers", "Authorization")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want 204: %s", w.Code, w.Body)
+	}
+	if reached {
+		t.Error("the preflight request reached the route")
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://join.denniscodeworld.de",
+		"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE",
+		"Access-Control-Allow-Headers": "Authorization, Content-Type, If-Match, Last-Event-ID",
+		"Access-Control-Max-Age":       "600",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("got %s %q, want %q", name, got, value)
+		}
+	}
+	if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Origin") ||
+		!slices.Contains(vary, "Access-Control-Request-Method") {
+		t.Errorf("got Vary %q, want Origin and Access-Control-Request-Method", vary)
+	}
+}
+
+// The `TestCORSHeaders` test checks the headers of ordinary requests: an allowed origin may read the
+// ETag, Location and X-Total-Count headers, another origin gets no CORS headers at all, and both
+// answers vary by origin.
+func TestCORSHeaders(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := withCORS(ok, corsSettings)
+
+	r := httptest.NewRequest("GET", "/tasks", nil)
+	r.Header.Set("Origin", "https://join.denniscodeworld.de")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	exposed := strings.Split(w.Header().Get("Access-Control-Expose-Headers"), ", ")
+	for _, name := range []string{"ETag", "Location", "X-Total-Count"} {
+		if !slices.Contains(exposed, name) {
+			t.Errorf("Access-Control-Expose-Headers %q does not list %s", exposed, name)
+		}
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("allowed origin: got Vary %q, want Origin", got)
+	}
+
+	r = httptest.NewRequest("GET", "/tasks", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	for name := range w.Header() {
+		if strings.HasPrefix(name, "Access-Control-") {
+			t.Errorf("other origin got %s: %q", name, w.Header().Get(name))
+		}
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("other origin: got Vary %q, want Origin", got)
+	}
+}
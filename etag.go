@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The `etag` function returns the entity tag for the given version of a task or contact.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// The `checkIfMatch` function evaluates the `If-Match` request header `ifMatch` against the stored
+// version of an entity. Without the header every write is allowed, which keeps the old last-write-wins
+// behaviour for clients that do not send it. With the header, the write is only allowed if one of the
+// listed entity tags (or "*") matches the existing entity; otherwise a 412 Precondition Failed error
+// is returned, so that the client can fetch the current version and let the user merge the changes.
+func checkIfMatch(ifMatch string, exists bool, version int64) error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" {
+		return nil
+	}
+	if exists {
+		if ifMatch == "*" {
+			return nil
+		}
+
+		// Weak tags are compared like strong ones, since the version identifies the content exactly.
+		current := etag(version)
+		for _, tag := range strings.Split(ifMatch, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == current {
+				return nil
+			}
+		}
+		return &httpError{http.StatusPreconditionFailed, "precondition_failed",
+			fmt.Sprintf("the resource has been modified, its current ETag is %s", current)}
+	}
+	return &httpError{http.StatusPreconditionFailed, "precondition_failed", "the resource does not exist"}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The `TestCheckIfMatch` test covers the If-Match header on its own: missing, matching, stale, weak
+// and listed tags, and "*" for an existing and a missing entity.
+func TestCheckIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		exists  bool
+		ok      bool
+	}{
+		{"no header", "", true, true},
+		{"no header, missing entity", "", false, true},
+		{"current tag", `"3"`, true, true},
+		{"stale tag", `"2"`, true, false},
+		{"unquoted tag", `3`, true, false},
+		{"weak tag", `W/"3"`, true, true},
+		{"stale weak tag", `W/"2"`, true, false},
+		{"list with current tag", `"1", "3"`, true, true},
+		{"list without current tag", `"1","2"`, true, false},
+		{"star", "*", true, true},
+		{"star, missing entity", "*", false, false},
+		{"tag, missing entity", `"3"`, false, false},
+	}
+	for _, tt := range tests {
+		err := checkIfMatch(tt.ifMatch, tt.exists, 3)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		httpErr, isHTTP := err.(*httpError)
+		if !isHTTP || httpErr.status != http.StatusPreconditionFailed {
+			t.Errorf("%s: got %v, want 412", tt.name, err)
+		}
+	}
+}
+
+// The `TestIfMatchWrites` test sends writes with an If-Match header to `PUT /tasks/{id}`. A write with
+// the current ETag bumps the version and returns the new ETag, and the old ETag is refused afterwards.
+func TestIfMatchWrites(t *testing.T) {
+	newTestBoard(t)
+	put := func(target, ifMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", target, strings.NewReader(taskJSON("Guarded")))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("If-Match", ifMatch)
+		r.SetPathValue("id", "tk-guarded")
+		w := httptest.NewRecorder()
+		replaceTask(w, r)
+		return w
+	}
+
+	if w := put("/tasks/tk-guarded?upsert=true", "*"); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("upsert with * of a missing task: got %d %s, want 412", w.Code, w.Body)
+	}
+	w := put("/tasks/tk-guarded?upsert=true", "")
+	if w.Code != http.StatusCreated || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("upsert: got %d and ETag %q, want 201 with \"1\"", w.Code, w.Header().Get("ETag"))
+	}
+
+	w = put("/tasks/tk-guarded", `"1"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("current ETag: got %d and ETag %q, want 200 with \"2\"", w.Code, w.Header().Get("ETag"))
+	}
+	if w := put("/tasks/tk-guarded", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale ETag: got %d %s, want 412", w.Code, w.Body)
+	}
+	w = put("/tasks/tk-guarded", `W/"2"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("weak ETag: got %d and ETag %q, want 200 with \"3\"", w.Code, w.Header().Get("ETag"))
+	}
+	if w := put("/tasks/tk-guarded", `"1", "3"`); w.Code != http.StatusOK {
+		t.Errorf("list of ETags: got %d %s, want 200", w.Code, w.Body)
+	}
+	if w := put("/tasks/tk-guarded", "*"); w.Code != http.StatusOK {
+		t.Errorf("* of an existing task: got %d %s, want 200", w.Code, w.Body)
+	}
+}
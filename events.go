@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"minibackend/events"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The `eventHistory` constant is the number of events the bus of a board keeps for clients that
+// resume a lost connection.
+const eventHistory = 1000
+
+// The `heartbeatInterval` variable is how often an idle event stream sends a comment line, so that
+// proxies do not close the connection.
+var heartbeatInterval = 25 * time.Second
+
+// The `publishChanges` function is a write hook that publishes one event per change of a committed
+// transaction on the event bus of the board, as a typed event such as "task.created", "task.updated"
+// or "contact.deleted". A put is published with the stored entity as data, a delete with the ID only.
+// Write hooks run in commit order, so event IDs increase with the versions of an entity and a client
+// applying the events in order never rolls a card back to an older version.
+func publishChanges(b *board, changes []change) {
+	for _, ch := range changes {
+		switch {
+		case ch.Task != nil:
+			b.bus.Publish(eventType(ch), ch.Task)
+		case ch.Contact != nil:
+			b.bus.Publish(eventType(ch), ch.Contact)
+		case ch.Category != nil:
+			b.bus.Publish(eventType(ch), ch.Category)
+		default:
+			b.bus.Publish(eventType(ch), map[string]string{"id": ch.ID})
+		}
+	}
+}
+
+// The `eventType` function returns the event type of `ch`, the entity kind followed by "created",
+// "updated" or "deleted".
+func eventType(ch change) string {
+	switch {
+	case ch.Task == nil && ch.Contact == nil && ch.Category == nil:
+		return ch.Kind + ".deleted"
+	case ch.Created:
+		return ch.Kind + ".created"
+	}
+	return ch.Kind + ".updated"
+}
+
+// The `eventsHandler` function handles `GET /events`, a Server-Sent Events stream of every change to
+// the board. A reconnecting client sends the ID of the last event it received in the `Last-Event-ID`
+// header (or the `last_event_id` parameter) and first gets the events it missed. If they are no longer
+// known, it gets a "resync" event instead and has to reload the board.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var after uint64
+	if lastID != "" {
+		var err error
+		if after, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_query", "Last-Event-ID: must be an event ID")
+			return
+		}
+	}
+
+	sub, missed, complete, cancel := b.bus.Subscribe(after)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	if !complete {
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, e := range missed {
+		writeEvent(w, e)
+	}
+	flusher.Flush()
+
+	// The stream ends when the client goes away or when the bus drops the subscription because the
+	// client could not keep up; in the latter case the client reconnects and resumes.
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// The `writeEvent` function writes `e` in the Server-Sent Events format, with its data encoded as a
+// single line of JSON.
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		data = []byte("null")
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
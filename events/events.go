@@ -0,0 +1,102 @@
+// Package events implements the in-process event bus behind the real-time endpoints. Handlers publish
+// typed events after every committed change, and every subscriber, e.g. an open `GET /events` stream,
+// receives them in order. The most recent events are kept, so that a client that lost its connection
+// can resume where it left off.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// The `Event` struct is one published event. `ID` increases with every event; it is seeded from the
+// clock at startup, so IDs handed out before a restart are smaller than the ones handed out after it.
+type Event struct {
+	ID   uint64
+	Type string
+	Data any
+}
+
+// The `subscriberBuffer` constant is the number of events a subscriber may fall behind before it is
+// dropped. A dropped subscriber's channel is closed, and the client resumes with its last event ID.
+const subscriberBuffer = 64
+
+// The `Bus` struct distributes events to its subscribers and remembers the last `historySize` events.
+// A `Bus` is safe for concurrent use.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[chan Event]struct{}
+}
+
+// The `NewBus` function returns a bus that keeps the last `historySize` events for resuming clients.
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		lastID:      uint64(time.Now().UnixMicro()),
+		historySize: historySize,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// The `Publish` method assigns the next ID to a new event of type `typ`, stores it in the history and
+// sends it to every subscriber. Subscribers that cannot keep up are dropped instead of blocking the
+// publisher.
+func (b *Bus) Publish(typ string, data any) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e := Event{ID: b.lastID, Type: typ, Data: data}
+	b.history = append(b.history, e)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return e
+}
+
+// The `Subscribe` method registers a new subscriber. If `lastID` is not zero, the events published
+// after it are returned as `missed`, so that the subscriber sees every event exactly once; `complete`
+// is false if some of those events are no longer in the history and the subscriber has to reload its
+// state instead. `cancel` unregisters the subscriber and must be called when it is done.
+func (b *Bus) Subscribe(lastID uint64) (ch <-chan Event, missed []Event, complete bool, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastID != 0 {
+		switch {
+		case lastID > b.lastID:
+			complete = false
+		case len(b.history) == 0 || lastID+1 < b.history[0].ID:
+			complete = lastID == b.lastID
+		}
+		for _, e := range b.history {
+			if e.ID > lastID {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	b.subscribers[c] = struct{}{}
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[c]; ok {
+			delete(b.subscribers, c)
+			close(c)
+		}
+	}
+	return c, missed, complete, cancel
+}
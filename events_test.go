@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"minibackend/structures"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// The `TestEventsFollowCommitOrder` test patches one task from many goroutines while subscribed to the
+// event bus of the board. The "task.updated" events must carry the versions of the task in increasing
+// order, since clients apply them in the order of their IDs.
+func TestEventsFollowCommitOrder(t *testing.T) {
+	b := newTestBoard(t)
+	task := &structures.Task{Title: "Busy", Status: structures.StatusToDo, Prio: structures.PriorityLow, Category: testCategory}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, _, _, cancel := b.bus.Subscribe(0)
+	defer cancel()
+
+	const n = 30
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			patch := fmt.Sprintf(`{"description": "edit %d"}`, i)
+			if w := serve(patchTask, "PATCH", "/tasks/"+task.ID_task, patch, "id", task.ID_task); w.Code != http.StatusOK {
+				t.Errorf("PATCH: got %d %s", w.Code, w.Body)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var lastID uint64
+	var lastVersion int64 = 1
+	for i := 0; i < n; i++ {
+		e, ok := <-sub
+		if !ok {
+			t.Fatal("subscription dropped")
+		}
+		updated, isTask := e.Data.(*structures.Task)
+		if e.Type != "task.updated" || !isTask {
+			t.Fatalf("got event %s with %T", e.Type, e.Data)
+		}
+		if e.ID <= lastID || updated.Version != lastVersion+1 {
+			t.Fatalf("event %d carries version %d after event %d with version %d", e.ID, updated.Version, lastID, lastVersion)
+		}
+		lastID, lastVersion = e.ID, updated.Version
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"minibackend/storage"
+	"minibackend/structures"
+)
+
+// The `change` struct describes one write of a committed transaction. Exactly one of `Task`,
+// `Contact` and `Category` is set for a put, and `Created` tells whether the put added a new entity;
+// for a delete all of them are nil and only `Kind` and `ID` say what was removed.
+type change struct {
+	Kind     string
+	ID       string
+	Created  bool
+	Task     *structures.Task
+	Contact  *structures.Contact
+	Category *structures.Category
+}
+
+// The kinds of entity a `change` can refer to.
+const (
+	kindTask     = "task"
+	kindContact  = "contact"
+	kindCategory = "category"
+)
+
+// The `writeHooks` variable lists the functions called after every committed `update`, in order, with
+// the board and the changes made by the transaction. Hooks keep derived state such as the search index
+// in step with the store; they run after the commit and cannot fail it. Hooks run while the board is
+// locked for writing, so they must not write to the board themselves.
+var writeHooks []func(b *board, changes []change)
+
+// The `update` method runs `fn` in a read-write transaction of the board's store, like `Store.Update`,
+// and passes the writes made by `fn` to the `writeHooks` once the transaction has been committed. All
+// handlers write tasks, contacts and categories through `update`, so no write can bypass the hooks.
+// The board's write lock is held from the start of the transaction until the last hook has returned,
+// so the hooks see the commits in the order in which they were made: otherwise the index could keep
+// an older version of a task written by two concurrent requests, and the event bus could publish the
+// older version last.
+func (b *board) update(fn func(tx storage.Tx) error) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	var rec *recordingTx
+	err := b.store.Update(func(tx storage.Tx) error {
+		rec = &recordingTx{Tx: tx}
+		return fn(rec)
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range writeHooks {
+		hook(b, rec.changes)
+	}
+	return nil
+}
+
+// The `recordingTx` struct wraps a `storage.Tx` and records every successful write made through it.
+type recordingTx struct {
+	storage.Tx
+	changes []change
+}
+
+func (tx *recordingTx) PutTask(t *structures.Task) error {
+	_, err := tx.Tx.Task(t.ID_task)
+	created := errors.Is(err, storage.ErrNotFound)
+	if err := tx.Tx.PutTask(t); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, change{Kind: kindTask, ID: t.ID_task, Created: created, Task: t.Clone()})
+	return nil
+}
+
+func (tx *recordingTx) DeleteTask(id string) error {
+	if err := tx.Tx.DeleteTask(id); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, change{Kind: kindTask, ID: id})
+	return nil
+}
+
+func (tx *recordingTx) PutContact(c *structures.Contact) error {
+	_, err := tx.Tx.Contact(c.ID_contact)
+	created := errors.Is(err, storage.ErrNotFound)
+	if err := tx.Tx.PutContact(c); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, change{Kind: kindContact, ID: c.ID_contact, Created: created, Contact: c.Clone()})
+	return nil
+}
+
+func (tx *recordingTx) DeleteContact(id string) error {
+	if err := tx.Tx.DeleteContact(id); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, change{Kind: kindContact, ID: id})
+	return nil
+}
+
+func (tx *recordingTx) PutCategory(c *structures.Category) error {
+	_, err := tx.Tx.Category(c.ID_category)
+	created := errors.Is(err, storage.ErrNotFound)
+	if err := tx.Tx.PutCategory(c); err != nil {
+		return err
+	}
+	copied := *c
+	tx.changes = append(tx.changes, change{Kind: kindCategory, ID: c.ID_category, Created: created, Category: &copied})
+	return nil
+}
+
+func (tx *recordingTx) DeleteCategory(id string) error {
+	if err := tx.Tx.DeleteCategory(id); err != nil {
+		return err
+	}
+	tx.changes = append(tx.changes, change{Kind: kindCategory, ID: id})
+	return nil
+}
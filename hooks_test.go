@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"minibackend/structures"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The `TestHooksSeeCommitOrder` test patches one task from many goroutines and records the versions
+// the write hooks are called with. Every version must arrive exactly once and in the order in which
+// the versions were committed, or the index and the event stream would end up with a stale task.
+func TestHooksSeeCommitOrder(t *testing.T) {
+	b := newTestBoard(t)
+	task := &structures.Task{Title: "Busy", Status: structures.StatusToDo, Prio: structures.PriorityLow, Category: testCategory}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var versions []int64
+	writeHooks = append(writeHooks, func(b *board, changes []change) {
+		// The hook of the first patch is slow, which gives the later patches every chance to overtake
+		// it if the hooks were not serialized with the commits.
+		if len(changes) > 0 && changes[0].Task != nil && changes[0].Task.Version == 2 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, ch := range changes {
+			if ch.Task != nil {
+				versions = append(versions, ch.Task.Version)
+			}
+		}
+	})
+
+	const n = 30
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			patch := fmt.Sprintf(`{"description": "edit %d"}`, i)
+			if w := serve(patchTask, "PATCH", "/tasks/"+task.ID_task, patch, "id", task.ID_task); w.Code != http.StatusOK {
+				t.Errorf("PATCH: got %d %s", w.Code, w.Body)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(versions) != n {
+		t.Fatalf("hooks saw %d versions, want %d", len(versions), n)
+	}
+	for i, v := range versions {
+		if v != int64(i+2) {
+			t.Fatalf("hooks saw versions %v, want 2 to %d in order", versions, n+1)
+		}
+	}
+}
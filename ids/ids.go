@@ -0,0 +1,111 @@
+// Package ids generates the identifiers of tasks, contacts and subtasks. Entity IDs are a short type
+// prefix followed by a ULID: 48 bits of millisecond timestamp and 80 random bits, encoded in
+// Crockford base32. They sort by creation time and cannot collide when two entities are created in the
+// same second, unlike the old "tk" + Unix seconds scheme. IDs created by that scheme stay valid, since
+// the server treats every ID as an opaque string.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// The Crockford base32 alphabet used by ULIDs. It leaves out I, L, O and U to avoid confusion.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// The `generator` struct remembers the last ULID handed out so that IDs created within the same
+// millisecond are still strictly increasing.
+type generator struct {
+	mu       sync.Mutex
+	lastMs   uint64
+	lastRand [10]byte
+
+	lastSubtask int64
+}
+
+var defaultGenerator generator
+
+// The `New` function returns a new unique ID with the given prefix, e.g. New("tk") returns something
+// like "tk01HQ3Z8K4M6W2V9X0Y7R5T1B3C".
+func New(prefix string) string {
+	return prefix + defaultGenerator.ulid(time.Now())
+}
+
+// The `Unique` function returns a new ID with the given prefix for which `exists` reports false. It is
+// meant to be called inside a store transaction, so that an ID imported from elsewhere can never be
+// handed out a second time.
+func Unique(prefix string, exists func(id string) (bool, error)) (string, error) {
+	for {
+		id := New(prefix)
+		taken, err := exists(id)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
+
+// The `NewSubtaskID` function returns a new subtask ID. Subtask IDs are numbers that the frontend used
+// to create from `Date.now()`, so the server keeps that format: the current Unix time in milliseconds,
+// bumped by one whenever it would not be larger than the previous ID. This keeps the IDs sortable by
+// creation time and unique within the process.
+func NewSubtaskID() int64 {
+	return defaultGenerator.subtask(time.Now())
+}
+
+func (g *generator) subtask(now time.Time) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := now.UnixMilli()
+	if id <= g.lastSubtask {
+		id = g.lastSubtask + 1
+	}
+	g.lastSubtask = id
+	return id
+}
+
+// The `ulid` method returns the 26 character ULID for `now`. Within the same millisecond the random
+// part of the previous ULID is incremented instead of drawn anew, which keeps the IDs monotonic.
+func (g *generator) ulid(now time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(now.UnixMilli())
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		for i := len(g.lastRand) - 1; i >= 0; i-- {
+			g.lastRand[i]++
+			if g.lastRand[i] != 0 {
+				break
+			}
+		}
+	} else {
+		if _, err := rand.Read(g.lastRand[:]); err != nil {
+			panic(err)
+		}
+		g.lastMs = ms
+	}
+
+	// The 128 bits are laid out as the 6 timestamp bytes followed by the 10 random bytes and encoded
+	// five bits at a time, starting with the two leading padding bits of the 130 bit representation.
+	var raw [16]byte
+	var tsBytes [8]byte
+	binary.BigEndian.PutUint64(tsBytes[:], ms)
+	copy(raw[:6], tsBytes[2:])
+	copy(raw[6:], g.lastRand[:])
+
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = alphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,85 @@
+package ids
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The `TestULIDMonotonic` test creates many ULIDs within the same millisecond and one after the clock
+// went back, which all have to be strictly increasing and share the timestamp part.
+func TestULIDMonotonic(t *testing.T) {
+	var g generator
+	now := time.UnixMilli(1707151555000)
+
+	prev := g.ulid(now)
+	for i := 0; i < 1000; i++ {
+		at := now
+		if i == 500 {
+			at = now.Add(-time.Second)
+		}
+		id := g.ulid(at)
+		if len(id) != 26 || strings.Trim(id, alphabet) != "" {
+			t.Fatalf("%q is not a ULID", id)
+		}
+		if id <= prev {
+			t.Fatalf("ULID %s does not sort after %s", id, prev)
+		}
+		if id[:10] != prev[:10] {
+			t.Fatalf("ULID %s has another timestamp than %s", id, prev)
+		}
+		prev = id
+	}
+
+	if later := g.ulid(now.Add(time.Millisecond)); later[:10] <= prev[:10] {
+		t.Errorf("ULID %s of the next millisecond has no later timestamp than %s", later, prev)
+	}
+}
+
+// The `TestSubtaskIDsConcurrent` test requests subtask IDs from many goroutines at once; no ID may be
+// handed out twice. Run it with `go test -race` to also catch unsynchronized access.
+func TestSubtaskIDsConcurrent(t *testing.T) {
+	const goroutines, perGoroutine = 8, 500
+	results := make(chan int64, goroutines*perGoroutine)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				results <- NewSubtaskID()
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := make(map[int64]bool, goroutines*perGoroutine)
+	for id := range results {
+		if seen[id] {
+			t.Fatalf("subtask ID %d was handed out twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+// The `TestUnique` test checks that `Unique` draws new IDs until one is free and passes errors of the
+// lookup on.
+func TestUnique(t *testing.T) {
+	calls := 0
+	id, err := Unique("tk", func(id string) (bool, error) {
+		calls++
+		return calls < 3, nil
+	})
+	if err != nil || calls != 3 || !strings.HasPrefix(id, "tk") {
+		t.Errorf("got ID %q and error %v after %d calls, want a tk ID after 3 calls", id, err, calls)
+	}
+
+	lookupErr := errors.New("store unavailable")
+	_, err = Unique("tk", func(string) (bool, error) { return false, lookupErr })
+	if err != lookupErr {
+		t.Errorf("got error %v, want %v", err, lookupErr)
+	}
+}
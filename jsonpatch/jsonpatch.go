@@ -0,0 +1,401 @@
+// Package jsonpatch applies partial updates to JSON documents. It implements JSON Merge Patch
+// (RFC 7396), where the patch is a partial document and null removes a member, and JSON Patch
+// (RFC 6902), where the patch is a list of add/remove/replace/move/copy/test operations addressed by
+// JSON Pointers (RFC 6901).
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The media types of the two patch formats, as sent in the Content-Type header.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// The `ErrTestFailed` error is returned by `Apply` when a "test" operation does not match, which
+// usually means the client patched a stale copy of the document.
+var ErrTestFailed = errors.New("jsonpatch: test operation failed")
+
+// The `MergePatch` function applies the merge patch `patch` to the document `doc` and returns the
+// patched document. Members of `patch` that are objects are merged recursively, null members are
+// removed from the document, and every other value replaces the member of the same name.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid merge patch: %w", err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+// The `mergeValue` function implements the MergePatch algorithm of RFC 7396, section 2.
+func mergeValue(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+		} else {
+			targetObj[name] = mergeValue(targetObj[name], value)
+		}
+	}
+	return targetObj
+}
+
+// The `operation` struct is one entry of a JSON Patch. `Value` is kept raw so that an explicit null
+// can be told apart from a missing member.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// The `Apply` function applies the JSON Patch `patch` to the document `doc`. The operations are
+// applied in order and the whole patch fails if any of them fails, in which case `doc` is left as it
+// was.
+func Apply(doc, patch []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid document: %w", err)
+	}
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("jsonpatch: invalid patch: %w", err)
+	}
+
+	for i, op := range ops {
+		root, err = applyOperation(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+// The `applyOperation` function applies a single JSON Patch operation to `root` and returns the new
+// root, which differs from `root` when the operation targets the whole document.
+func applyOperation(root any, op operation) (any, error) {
+	if op.Path == nil {
+		return nil, errors.New(`missing "path"`)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, errors.New(`missing "value"`)
+		}
+		return decode(op.Value)
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, errors.New(`missing "from"`)
+		}
+		return parsePointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, v)
+
+	case "remove":
+		root, _, err := remove(root, path)
+		return root, err
+
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := get(root, path); err != nil {
+			return nil, err
+		}
+		root, _, err := remove(root, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, v)
+
+	case "move":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(src) && reflect.DeepEqual(path[:len(src)], src) {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		root, v, err := remove(root, src)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, v)
+
+	case "copy":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := get(root, src)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, deepCopy(v))
+
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(actual, v) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// The `parsePointer` function splits a JSON Pointer into its unescaped reference tokens. The empty
+// pointer "" refers to the whole document and yields no tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// The `get` function returns the value at `path` in `root`.
+func get(root any, path []string) (any, error) {
+	current := root
+	for _, token := range path {
+		switch node := current.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			current = v
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q", token)
+		}
+	}
+	return current, nil
+}
+
+// The `add` function inserts `value` at `path` and returns the new root. Object members are added or
+// replaced, array elements are inserted before the given index, or appended for the index "-".
+func add(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+		return root, nil
+	case []any:
+		i := len(node)
+		if last != "-" {
+			i, err = arrayIndex(last, len(node))
+			if err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return setParent(root, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("cannot add %q to a scalar value", last)
+}
+
+// The `remove` function deletes the value at `path` and returns the new root and the removed value.
+func remove(root any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, root, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q does not exist", last)
+		}
+		delete(node, last)
+		return root, v, nil
+	case []any:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		root, err := setParent(root, path[:len(path)-1], node)
+		return root, v, err
+	}
+	return nil, nil, fmt.Errorf("cannot remove %q from a scalar value", last)
+}
+
+// The `setParent` function stores a grown or shrunk array back at `path`, since appending to a slice
+// may have moved it.
+func setParent(root any, path []string, array []any) (any, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = array
+	case []any:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = array
+	}
+	return root, nil
+}
+
+// The `arrayIndex` function parses an array index token and checks it against the largest allowed
+// index `max`. Leading zeros are not allowed by RFC 6901.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+// The `decode` function parses a JSON value, keeping numbers as `json.Number` so that large integers
+// such as subtask IDs survive the round trip without losing precision.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// The `deepCopy` function copies a decoded JSON value, so that a copied value and its source do not
+// share maps or slices.
+func deepCopy(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(node))
+		for k, e := range node {
+			c[k] = deepCopy(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(node))
+		for i, e := range node {
+			c[i] = deepCopy(e)
+		}
+		return c
+	}
+	return v
+}
+
+// The `equal` function compares two decoded JSON values as required by the "test" operation. Numbers
+// are compared by their exact decimal value, so 1 and 1.0 are equal, but two large integers such as
+// IDs or versions that differ only beyond the precision of a float64 are not.
+func equal(a, b any) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(na.String())
+		rb, okB := new(big.Rat).SetString(nb.String())
+		return okA && okB && ra.Cmp(rb) == 0
+	}
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,183 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The `sameJSON` function reports whether `a` and `b` encode the same JSON value, ignoring the order
+// of object members and the formatting. Numbers are compared by their text, so that large integers
+// keep all their digits.
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	x, err := decode(a)
+	if err != nil {
+		t.Fatalf("%s: %v", a, err)
+	}
+	y, err := decode(b)
+	if err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func TestApply(t *testing.T) {
+	const doc = `{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string // empty if the patch must fail
+	}{
+		{"add member", `[{"op": "add", "path": "/prio", "value": "Low"}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "a/b": 1, "m~n": 2, "prio": "Low"}`},
+		{"add replaces member", `[{"op": "add", "path": "/title", "value": "Do"}]`,
+			`{"title": "Do", "tags": ["a", "b"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"add into array", `[{"op": "add", "path": "/tags/1", "value": "x"}]`,
+			`{"title": "Plan", "tags": ["a", "x", "b"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"add at end of array", `[{"op": "add", "path": "/tags/2", "value": "x"}]`,
+			`{"title": "Plan", "tags": ["a", "b", "x"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"append with -", `[{"op": "add", "path": "/tags/-", "value": "x"}]`,
+			`{"title": "Plan", "tags": ["a", "b", "x"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"add past end of array", `[{"op": "add", "path": "/tags/3", "value": "x"}]`, ""},
+		{"add with leading zero", `[{"op": "add", "path": "/tags/01", "value": "x"}]`, ""},
+		{"add below missing member", `[{"op": "add", "path": "/none/x", "value": 1}]`, ""},
+		{"add without value", `[{"op": "add", "path": "/x"}]`, ""},
+		{"add null", `[{"op": "add", "path": "/x", "value": null}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "a/b": 1, "m~n": 2, "x": null}`},
+		{"replace whole document", `[{"op": "replace", "path": "", "value": {"x": 1}}]`, `{"x": 1}`},
+
+		{"remove member", `[{"op": "remove", "path": "/meta"}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "a/b": 1, "m~n": 2}`},
+		{"remove array element", `[{"op": "remove", "path": "/tags/0"}]`,
+			`{"title": "Plan", "tags": ["b"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"remove with -", `[{"op": "remove", "path": "/tags/-"}]`, ""},
+		{"remove out of range", `[{"op": "remove", "path": "/tags/2"}]`, ""},
+		{"remove missing member", `[{"op": "remove", "path": "/none"}]`, ""},
+
+		{"replace member", `[{"op": "replace", "path": "/meta/version", "value": 2}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 2}, "a/b": 1, "m~n": 2}`},
+		{"replace array element", `[{"op": "replace", "path": "/tags/1", "value": "c"}]`,
+			`{"title": "Plan", "tags": ["a", "c"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"replace missing member", `[{"op": "replace", "path": "/none", "value": 1}]`, ""},
+
+		{"escaped slash", `[{"op": "replace", "path": "/a~1b", "value": 3}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "a/b": 3, "m~n": 2}`},
+		{"escaped tilde", `[{"op": "remove", "path": "/m~0n"}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "a/b": 1}`},
+		{"pointer without slash", `[{"op": "remove", "path": "title"}]`, ""},
+
+		{"move member", `[{"op": "move", "from": "/title", "path": "/meta/title"}]`,
+			`{"tags": ["a", "b"], "meta": {"version": 1, "title": "Plan"}, "a/b": 1, "m~n": 2}`},
+		{"move array element", `[{"op": "move", "from": "/tags/0", "path": "/tags/-"}]`,
+			`{"title": "Plan", "tags": ["b", "a"], "meta": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"move into own child", `[{"op": "move", "from": "/meta", "path": "/meta/inner"}]`, ""},
+		{"move without from", `[{"op": "move", "path": "/x"}]`, ""},
+
+		{"copy member", `[{"op": "copy", "from": "/meta", "path": "/copy"}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "copy": {"version": 1}, "a/b": 1, "m~n": 2}`},
+		{"copy is independent", `[{"op": "copy", "from": "/meta", "path": "/copy"}, {"op": "replace", "path": "/copy/version", "value": 5}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "meta": {"version": 1}, "copy": {"version": 5}, "a/b": 1, "m~n": 2}`},
+
+		{"test match", `[{"op": "test", "path": "/meta", "value": {"version": 1.0}}, {"op": "remove", "path": "/meta"}]`,
+			`{"title": "Plan", "tags": ["a", "b"], "a/b": 1, "m~n": 2}`},
+		{"test missing member", `[{"op": "test", "path": "/none", "value": 1}]`, ""},
+		{"unknown op", `[{"op": "rename", "path": "/title"}]`, ""},
+		{"not a list", `{"op": "remove", "path": "/title"}`, ""},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(doc), []byte(tt.patch))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if !sameJSON(t, got, []byte(tt.want)) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The `TestApplyFailedTest` test checks that a failing "test" operation makes the whole patch fail
+// with `ErrTestFailed`, without the operations before it changing the document.
+func TestApplyFailedTest(t *testing.T) {
+	doc := []byte(`{"title": "Plan", "version": 3}`)
+	original := append([]byte(nil), doc...)
+	patch := `[{"op": "replace", "path": "/title", "value": "Do"}, {"op": "test", "path": "/version", "value": 2}]`
+
+	got, err := Apply(doc, []byte(patch))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("got %s, %v, want ErrTestFailed", got, err)
+	}
+	if got != nil || !bytes.Equal(doc, original) {
+		t.Errorf("the failed patch changed the document to %s / %s", got, doc)
+	}
+}
+
+// The `TestEqualLargeNumbers` test compares integers that a float64 cannot tell apart.
+func TestEqualLargeNumbers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1", "1.0", true},
+		{"1e3", "1000", true},
+		{"9007199254740993", "9007199254740992", false},
+		{"1717000000000000001", "1717000000000000001", true},
+		{"1", `"1"`, false},
+	}
+	for _, tt := range tests {
+		a, err := decode([]byte(tt.a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := decode([]byte(tt.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := equal(a, b); got != tt.want {
+			t.Errorf("equal(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	const doc = `{"title": "Plan", "meta": {"version": 1, "owner": "ada"}, "tags": ["a"]}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"title": "Do"}`, `{"title": "Do", "meta": {"version": 1, "owner": "ada"}, "tags": ["a"]}`},
+		{"null deletes", `{"title": null}`, `{"meta": {"version": 1, "owner": "ada"}, "tags": ["a"]}`},
+		{"null for missing member", `{"none": null}`, doc},
+		{"nested merge", `{"meta": {"version": 2, "owner": null, "new": true}}`,
+			`{"title": "Plan", "meta": {"version": 2, "new": true}, "tags": ["a"]}`},
+		{"arrays are replaced", `{"tags": ["b", "c"]}`, `{"title": "Plan", "meta": {"version": 1, "owner": "ada"}, "tags": ["b", "c"]}`},
+		{"object over scalar", `{"title": {"text": "Plan"}}`,
+			`{"title": {"text": "Plan"}, "meta": {"version": 1, "owner": "ada"}, "tags": ["a"]}`},
+		{"non-object patch", `["x"]`, `["x"]`},
+		{"large integer", `{"id": 1717000000000000001}`,
+			`{"title": "Plan", "meta": {"version": 1, "owner": "ada"}, "tags": ["a"], "id": 1717000000000000001}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !sameJSON(t, got, []byte(tt.want)) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := MergePatch([]byte(doc), []byte(`{"title": `)); err == nil {
+		t.Error("an invalid merge patch was applied")
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+)
+
+// The handlers in this file serve the original verb-in-path endpoints (`/add_task`, `/del_task`,
+// `/update_task`, `/add_contact`, `/remove_contact`). They are deprecated in favour of the resource
+// routes registered in `main`, but stay available with their old request and response formats so
+// that the existing Kanban frontend keeps working.
+
+// The `deprecated` function wraps a legacy handler so that every response announces the deprecation
+// and points to the resource route that replaces it.
+func deprecated(handler http.HandlerFunc, successor string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		handler(w, r)
+	}
+}
+
+// The function `add_contact` in Go handles POST requests to add a new contact by decoding it from the
+// request body, storing it, and returning the updated contacts as a JSON response.
+func add_contact(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The above code is checking if the HTTP request method is POST. If the method is not POST, it returns
+	// a "Method not allowed" error with status code 405 (Method Not Allowed).
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The above code decodes the JSON data from the request body into a new Contact struct. If there is
+	// an error during the decoding process, it will return a Bad Request HTTP error with the error
+	// message.
+	newContact := &structures.Contact{}
+	err := json.NewDecoder(r.Body).Decode(newContact)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The new contact is validated and stored under a server-generated ID. An invalid contact results in
+	// a 422 response listing the failed fields, any other failure in an HTTP 500 Internal Server Error
+	// response with the message "Error writing updated contacts".
+	if err := insertContact(b, newContact); err != nil {
+		writeStoreError(w, err, "Error writing updated contacts")
+		return
+	}
+
+	// Like before, the response contains all contacts rather than just the new one.
+	var existingContacts map[string]*structures.Contact
+	err = b.store.View(func(tx storage.Tx) error {
+		var err error
+		existingContacts, err = tx.Contacts()
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Error reading existing contacts", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, existingContacts)
+}
+
+// The `add_task` function handles adding a new task to the store in a Go web application and returns
+// all tasks including the new one.
+func add_task(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The above code is checking if the HTTP request method is POST. If the method is not POST, it
+	// returns a "Method not allowed" error with status code 405 (Method Not Allowed).
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The code decodes the JSON data from the request body into a new task object using
+	// `json.NewDecoder(r.Body).Decode(newTask)`. If there is an error during decoding, it will return a
+	// Bad Request response with the error message.
+	newTask := &structures.Task{}
+	err := json.NewDecoder(r.Body).Decode(newTask)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The new task is validated and stored under a server-generated ID. An invalid task results in a
+	// 422 response listing the failed fields, any other failure in a 500 Internal Server Error response
+	// with the message "Error writing updated tasks".
+	if err := insertTask(b, newTask); err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	// Like before, the response contains all tasks rather than just the new one.
+	var existingTasks map[string]*structures.Task
+	err = b.store.View(func(tx storage.Tx) error {
+		var err error
+		existingTasks, err = tx.Tasks()
+		return err
+	})
+	if err != nil {
+		http.Error(w, "Error reading existing tasks", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, existingTasks)
+}
+
+// The `updateTask` function in Go handles updating a task by reading the request body, parsing JSON
+// data, storing the task, and sending a success response.
+func updateTask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The above code is checking if the HTTP request method is POST. If the method is not POST, it returns
+	// a "Method not allowed" error with a status code of 405 (Method Not Allowed).
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The above code is reading the request body from an HTTP request in Go. It uses the `io.ReadAll`
+	// function to read the request body and stores the result in the `body` variable. If there is an error
+	// while reading the request body, it will return an HTTP error response with a status code of 500
+	// (Internal Server Error) and a message "Error reading request body".
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	// The above code is attempting to unmarshal JSON data from the `body` variable into a `task` struct in
+	// Go. If there is an error during the unmarshalling process, it will return a "Invalid JSON format"
+	// error with a status code of 400 (Bad Request).
+	var task structures.Task
+	if err := json.Unmarshal(body, &task); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	// The task is validated and stored under its `ID_task`, replacing the existing task with that ID.
+	// This endpoint never creates tasks: an empty ID results in 400 Bad Request and an unknown one in 404
+	// Not Found. An invalid task results in a 422 response listing the failed fields, any other failure
+	// in an HTTP error response with status code 500 (Internal Server Error).
+	if _, err := saveTask(b, &task, false, r.Header.Get("If-Match")); err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	// The above code is written in Go programming language and it is setting the HTTP status code to 200
+	// (OK), and writing the message "Task updated successfully" to the response body.
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "Task updated successfully")
+}
+
+// The `deleteTask` function handles deleting a task based on the task ID provided in the request body
+// in a Go HTTP server.
+func deleteTask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The above code is checking if the HTTP request method is not DELETE. If the method is not DELETE, it
+	// returns a "Method not allowed" error with status code 405 (Method Not Allowed).
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The above code is reading the request body from an HTTP request in a Go program. It uses the
+	// `io.ReadAll` function to read the entire body of the request and stores it in the `body` variable.
+	// If there is an error while reading the request body, it will return an HTTP error response with a
+	// status code of 500 (Internal Server Error) and the message "Error reading request body".
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	// The above code in Go is defining a struct named `requestData` with a single field `TaskID` of type
+	// string. The `json:"task_id"` tag is used to specify the JSON key for this field when marshaling and
+	// unmarshaling JSON data.
+	var requestData struct {
+		TaskID string `json:"task_id"`
+	}
+
+	// The above code is attempting to unmarshal a JSON request body into a struct variable named
+	// `requestData`. If there is an error during the unmarshaling process, it will return a 400 Bad
+	// Request response with the message "Invalid JSON format".
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	// The above code is written in Go programming language. It checks if the `TaskID` field in the
+	// `requestData` object is empty. If it is empty, it returns a HTTP 400 Bad Request error with the
+	// message "Task ID not provided". This code snippet is used to validate the presence of a Task ID in
+	// the incoming request data.
+	if requestData.TaskID == "" {
+		http.Error(w, "Task ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	// The task is removed from the store. An unknown ID results in a 404 Not Found response, any other
+	// failure in a 500 Internal Server Error response with the message "Error writing updated tasks".
+	if err := removeTask(b, requestData.TaskID, r.Header.Get("If-Match")); err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	// The above code is written in Go programming language and it writes a response to the client with a
+	// message indicating that a task with a specific ID has been deleted successfully.
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Task with ID %s deleted successfully", requestData.TaskID)
+}
+
+// The `removeContact` function handles deleting a contact based on the contact ID provided in the
+// request body in a Go HTTP server.
+func removeContact(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The above code is checking if the HTTP request method is not DELETE. If the method is not DELETE, it
+	// returns a "Method not allowed" error with status code 405 (Method Not Allowed).
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The above code is reading the request body from an HTTP request in a Go program. It uses the
+	// `io.ReadAll` function to read the entire request body into a byte slice named `body`. If there is an
+	// error while reading the request body, it will return an internal server error response with the
+	// message "Error reading request body".
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	// The above code is written in Go and it is attempting to unmarshal JSON data from the `body` variable
+	// into a struct named `requestData`. The struct has a single field `ID_contact` with a tag specifying
+	// the JSON key to map to. If there is an error during the unmarshalling process, it will return a
+	// "Invalid JSON format" error with a status code of 400 (Bad Request).
+	var requestData struct {
+		ID_contact string `json:"ID_contact"`
+	}
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	// The above code is checking if the `ID_contact` field in the `requestData` is empty. If it is empty,
+	// it will return a HTTP 400 Bad Request error with the message "Contact ID not provided".
+	if requestData.ID_contact == "" {
+		http.Error(w, "Contact ID not provided", http.StatusBadRequest)
+		return
+	}
+
+	// The contact is removed from the store, with its tasks handled by the server's default policy. An
+	// unknown ID results in a 404 Not Found response, a contact that still has tasks under the reject
+	// policy in 409 Conflict, and any other failure in an HTTP 500 Internal Server Error response with
+	// the message "Error writing updated contacts".
+	if err := removeContactByID(b, requestData.ID_contact, r.Header.Get("If-Match"), defaultContactPolicy); err != nil {
+		writeStoreError(w, err, "Error writing updated contacts")
+		return
+	}
+
+	// The above code sends a response with a success message indicating that the contact with the
+	// specific ID has been deleted successfully.
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Contact with ID %s deleted successfully", requestData.ID_contact)
+}
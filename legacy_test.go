@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"minibackend/structures"
+	"net/http"
+	"testing"
+)
+
+// The `TestLegacyIDs` test covers the IDs sent to the deprecated endpoints: an empty ID is rejected
+// with 400 Bad Request, an unknown one with 404 Not Found, and none of them creates an entity.
+func TestLegacyIDs(t *testing.T) {
+	b := newTestBoard(t)
+	task := &structures.Task{Title: "Existing", Status: structures.StatusToDo, Prio: structures.PriorityLow, Category: testCategory}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+	contact := &structures.Contact{First_Name: "Existing", Email: "existing@example.com"}
+	if err := insertContact(b, contact); err != nil {
+		t.Fatal(err)
+	}
+
+	withID := func(id string) string {
+		return fmt.Sprintf(`{"ID_task": %q, "title": "Changed", "status": "Done", "prio": "Low", "category": %q}`, id, testCategory)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+		want    int
+	}{
+		{"update without ID", updateTask, "POST", withID(""), http.StatusBadRequest},
+		{"update unknown ID", updateTask, "POST", withID("tk-unknown"), http.StatusNotFound},
+		{"update existing ID", updateTask, "POST", withID(task.ID_task), http.StatusOK},
+		{"delete task without ID", deleteTask, "DELETE", `{"task_id": ""}`, http.StatusBadRequest},
+		{"delete unknown task", deleteTask, "DELETE", `{"task_id": "tk-unknown"}`, http.StatusNotFound},
+		{"delete existing task", deleteTask, "DELETE", fmt.Sprintf(`{"task_id": %q}`, task.ID_task), http.StatusOK},
+		{"remove contact without ID", removeContact, "DELETE", `{"ID_contact": ""}`, http.StatusBadRequest},
+		{"remove unknown contact", removeContact, "DELETE", `{"ID_contact": "cont-unknown"}`, http.StatusNotFound},
+		{"remove existing contact", removeContact, "DELETE", fmt.Sprintf(`{"ID_contact": %q}`, contact.ID_contact), http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := serve(tt.handler, tt.method, "/", tt.body); w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+
+	// The confirmation names the deleted contact as such, not as a task.
+	if err := insertContact(b, contact); err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"ID_contact": %q}`, contact.ID_contact)
+	want := fmt.Sprintf("Contact with ID %s deleted successfully", contact.ID_contact)
+	if w := serve(removeContact, "DELETE", "/", body); w.Body.String() != want {
+		t.Errorf("remove contact: got %q, want %q", w.Body, want)
+	}
+
+	if tasks, contacts := countEntities(t, b); tasks != 0 || contacts != 0 {
+		t.Errorf("got %d tasks and %d contacts, want none", tasks, contacts)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"minibackend/structures"
+	"net/http"
+)
+
+// The `metaEnums` function handles `GET /meta/enums`. It lists the allowed task statuses (in board
+// column order) and priorities, so that the frontend can build the board columns and priority buttons
+// from the server instead of hardcoding them.
+func metaEnums(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": structures.Statuses,
+		"prio":   structures.Priorities,
+	})
+}
@@ -0,0 +1,198 @@
+// Package migrations contains the one-shot data migrations that bring an existing data set up to date
+// with the current server. They are run by `go run ./cmd/migrate` against any storage backend.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"minibackend/ids"
+	"minibackend/storage"
+	"minibackend/structures"
+	"sort"
+)
+
+// The `Migration` struct describes a single migration. `Run` applies it inside the given transaction
+// and returns one human readable line per change it made.
+type Migration struct {
+	Name        string
+	Description string
+	Run         func(tx storage.Tx) ([]string, error)
+}
+
+// The `ErrDryRun` error is returned from a transaction to roll back the changes of a dry run.
+var ErrDryRun = errors.New("dry run")
+
+// The `All` variable lists every migration in the order in which they have to be applied. Migrations
+// are idempotent, so running them again on already migrated data changes nothing.
+var All = []Migration{
+	{
+		Name:        "ids",
+		Description: "make every task, contact and subtask ID unique and consistent with its key",
+		Run:         migrateIDs,
+	},
+	{
+		Name:        "assignees",
+		Description: "add an assignees list to tasks that only have the single assigned field",
+		Run:         migrateAssignees,
+	},
+	{
+		Name:        "roles",
+		Description: "give users created before roles existed a role: the earliest becomes admin, the others members",
+		Run:         migrateRoles,
+	},
+}
+
+// The `Lookup` function returns the migration with the given name.
+func Lookup(name string) (Migration, bool) {
+	for _, m := range All {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+// The `migrateIDs` function repairs the IDs written by older versions of the server without changing
+// any ID that is already valid, so links and bookmarks keep working. Tasks and contacts stored under an
+// empty ID get a new one, ID fields that disagree with the key they are stored under are corrected,
+// and subtasks without an ID or with an ID used twice within their task get a fresh one.
+func migrateIDs(tx storage.Tx) ([]string, error) {
+	var changes []string
+
+	tasks, err := tx.Tasks()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(tasks) {
+		task := tasks[key]
+		if key == "" {
+			newID, err := ids.Unique("tk", func(id string) (bool, error) {
+				_, ok := tasks[id]
+				return ok, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := tx.DeleteTask(key); err != nil {
+				return nil, err
+			}
+			key = newID
+			changes = append(changes, fmt.Sprintf("task with empty ID renamed to %s", newID))
+		}
+		if task.ID_task != key {
+			changes = append(changes, fmt.Sprintf("task %s: ID field %q corrected", key, task.ID_task))
+			task.ID_task = key
+		}
+		if task.FillSubtaskIDs(ids.NewSubtaskID) {
+			changes = append(changes, fmt.Sprintf("task %s: missing or duplicate subtask IDs replaced", key))
+		}
+
+		// Every task is written back, even if nothing was detected above: the JSON backend already
+		// corrects mismatching ID fields while loading, and only a write makes that permanent.
+		if err := tx.PutTask(task); err != nil {
+			return nil, err
+		}
+	}
+
+	contacts, err := tx.Contacts()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(contacts) {
+		contact := contacts[key]
+		if key == "" {
+			newID, err := ids.Unique("cont", func(id string) (bool, error) {
+				_, ok := contacts[id]
+				return ok, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := tx.DeleteContact(key); err != nil {
+				return nil, err
+			}
+			key = newID
+			changes = append(changes, fmt.Sprintf("contact with empty ID renamed to %s", newID))
+		}
+		if contact.ID_contact != key {
+			changes = append(changes, fmt.Sprintf("contact %s: ID field %q corrected", key, contact.ID_contact))
+			contact.ID_contact = key
+		}
+		if err := tx.PutContact(contact); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// The `migrateAssignees` function rewrites every task that was stored with only the single `assigned`
+// contact ID of older versions. Decoding already turns that field into the `assignees` list, so
+// writing the task back is enough to store it in the new form, which keeps `assigned` as the first
+// assignee for the old frontend.
+func migrateAssignees(tx storage.Tx) ([]string, error) {
+	var changes []string
+
+	tasks, err := tx.Tasks()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(tasks) {
+		task := tasks[key]
+		if !task.LegacyAssigned() {
+			continue
+		}
+		if err := tx.PutTask(task); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("task %s: assigned moved to assignees %v", key, task.Assignees))
+	}
+	return changes, nil
+}
+
+// The `migrateRoles` function gives a role to every user stored without one by an older version of
+// the server. Such users would otherwise be locked out of every route. If no user is an admin yet, the
+// earliest created user without a role becomes one, mirroring the first registration; the others become
+// members.
+func migrateRoles(tx storage.Tx) ([]string, error) {
+	var changes []string
+
+	users, err := tx.Users()
+	if err != nil {
+		return nil, err
+	}
+	hasAdmin := false
+	var pending []*structures.User
+	for _, key := range sortedKeys(users) {
+		user := users[key]
+		switch {
+		case user.Role == structures.RoleAdmin:
+			hasAdmin = true
+		case user.Role == "":
+			pending = append(pending, user)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Created.Before(pending[j].Created) })
+
+	for i, user := range pending {
+		user.Role = structures.RoleMember
+		if i == 0 && !hasAdmin {
+			user.Role = structures.RoleAdmin
+		}
+		if err := tx.PutUser(user); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("user %s: role set to %s", user.ID_user, user.Role))
+	}
+	return changes, nil
+}
+
+// The `sortedKeys` function returns the keys of `m` in ascending order, so that migrations process
+// entities and report changes in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,220 @@
+package migrations
+
+import (
+	"fmt"
+	"minibackend/storage"
+	"minibackend/structures"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The `openData` function writes `files`, a map from file names to their content, into a fresh data
+// directory and opens it with the JSON backend, so that the migrations see data exactly as an older
+// server wrote it.
+func openData(t *testing.T, files map[string]string) storage.Store {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := storage.OpenJSON(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// The `run` function applies the migration `name` to `s` and returns the changes it reported.
+func run(t *testing.T, s storage.Store, name string) []string {
+	t.Helper()
+	m, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("no migration named %q", name)
+	}
+	var changes []string
+	err := s.Update(func(tx storage.Tx) error {
+		var err error
+		changes, err = m.Run(tx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("migration %s: %v", name, err)
+	}
+	return changes
+}
+
+// The `TestMigrateIDs` test repairs tasks and contacts stored under an empty ID and subtasks with
+// missing or repeated IDs. Valid IDs, and the assignees that refer to them, must stay as they are, and
+// a second run must find nothing left to change.
+func TestMigrateIDs(t *testing.T) {
+	s := openData(t, map[string]string{
+		"tasks.json": `{
+			"tk1707151555": {"ID_task": "tk1707151555", "title": "Kept",
+				"assignees": ["cont1707057343"],
+				"subtasks": [{"subtaskId": 5, "title": "a"}, {"subtaskId": 5, "title": "b"},
+					{"title": "c"}]},
+			"tk1707151556": {"ID_task": "tk-stale", "title": "Mislabeled"},
+			"": {"ID_task": "", "title": "Anonymous"}
+		}`,
+		"contacts.json": `{
+			"cont1707057343": {"ID_contact": "cont1707057343", "first_name": "Michael"},
+			"": {"ID_contact": "", "first_name": "Nobody"}
+		}`,
+	})
+
+	changes := run(t, s, "ids")
+	if len(changes) != 5 {
+		t.Errorf("got changes %q, want the renames of the empty IDs, their corrected ID fields "+
+			"and the new subtask IDs", changes)
+	}
+
+	err := s.View(func(tx storage.Tx) error {
+		tasks, err := tx.Tasks()
+		if err != nil {
+			return err
+		}
+		contacts, err := tx.Contacts()
+		if err != nil {
+			return err
+		}
+		if len(tasks) != 3 || len(contacts) != 2 {
+			t.Fatalf("got %d tasks and %d contacts, want 3 and 2", len(tasks), len(contacts))
+		}
+		for key, task := range tasks {
+			if key == "" || task.ID_task != key {
+				t.Errorf("task %q has the ID field %q", key, task.ID_task)
+			}
+			if task.Title == "Anonymous" && !strings.HasPrefix(key, "tk") {
+				t.Errorf("the task with the empty ID was renamed to %q", key)
+			}
+		}
+		for key, contact := range contacts {
+			if key == "" || contact.ID_contact != key {
+				t.Errorf("contact %q has the ID field %q", key, contact.ID_contact)
+			}
+		}
+
+		// The valid IDs are kept, so the assignee still refers to the stored contact.
+		kept, ok := tasks["tk1707151555"]
+		if !ok {
+			t.Fatal("the task with a valid ID was renamed")
+		}
+		if _, ok := contacts[kept.Assignees[0]]; !ok || kept.Assignees[0] != "cont1707057343" {
+			t.Errorf("the task refers to the contact %q, which is not stored", kept.Assignees[0])
+		}
+		seen := map[int64]bool{}
+		for _, sub := range kept.Subtasks {
+			if sub.SubtaskId == 0 || seen[sub.SubtaskId] {
+				t.Errorf("subtask %q has the missing or repeated ID %d", sub.Title, sub.SubtaskId)
+			}
+			seen[sub.SubtaskId] = true
+		}
+		if kept.Subtasks[0].SubtaskId != 5 {
+			t.Errorf("the first subtask got the ID %d instead of keeping 5", kept.Subtasks[0].SubtaskId)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := run(t, s, "ids"); len(changes) != 0 {
+		t.Errorf("the second run reported %q", changes)
+	}
+}
+
+// The `TestMigrateAssignees` test rewrites a task that only has the single `assigned` field of older
+// versions, and leaves alone the tasks that already have an assignees list.
+func TestMigrateAssignees(t *testing.T) {
+	s := openData(t, map[string]string{
+		"tasks.json": `{
+			"tk1": {"ID_task": "tk1", "title": "Old", "assigned": "cont1"},
+			"tk2": {"ID_task": "tk2", "title": "New", "assigned": "cont1",
+				"assignees": ["cont1", "cont2"]},
+			"tk3": {"ID_task": "tk3", "title": "Nobody", "assignees": []}
+		}`,
+	})
+
+	changes := run(t, s, "assignees")
+	if len(changes) != 1 || !strings.HasPrefix(changes[0], "task tk1:") {
+		t.Errorf("got changes %q, want one for tk1", changes)
+	}
+	err := s.View(func(tx storage.Tx) error {
+		tasks, err := tx.Tasks()
+		if err != nil {
+			return err
+		}
+		want := map[string]string{"tk1": "[cont1]", "tk2": "[cont1 cont2]", "tk3": "[]"}
+		for id, assignees := range want {
+			if got := fmt.Sprint(tasks[id].Assignees); got != assignees {
+				t.Errorf("task %s has the assignees %s, want %s", id, got, assignees)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rewritten task is stored with its assignees list, so there is nothing left to migrate.
+	if changes := run(t, s, "assignees"); len(changes) != 0 {
+		t.Errorf("the second run reported %q", changes)
+	}
+}
+
+// The `TestMigrateRoles` test gives roles to users stored without one: the earliest of them becomes
+// admin if there is no admin yet, the others members, and users with a role keep it.
+func TestMigrateRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		users string
+		want  map[string]structures.Role
+	}{
+		{
+			name: "no admin yet",
+			users: `{
+				"us1": {"ID_user": "us1", "name": "Late", "created": "2024-03-02T00:00:00Z"},
+				"us2": {"ID_user": "us2", "name": "Early", "created": "2024-03-01T00:00:00Z"},
+				"us3": {"ID_user": "us3", "name": "Guest", "role": "guest",
+					"created": "2024-01-01T00:00:00Z"}
+			}`,
+			want: map[string]structures.Role{
+				"us1": structures.RoleMember, "us2": structures.RoleAdmin, "us3": structures.RoleGuest},
+		},
+		{
+			name: "admin exists",
+			users: `{
+				"us1": {"ID_user": "us1", "name": "Old", "created": "2024-01-01T00:00:00Z"},
+				"us2": {"ID_user": "us2", "name": "Admin", "role": "admin",
+					"created": "2024-03-01T00:00:00Z"}
+			}`,
+			want: map[string]structures.Role{"us1": structures.RoleMember, "us2": structures.RoleAdmin},
+		},
+	}
+	for _, tt := range tests {
+		s := openData(t, map[string]string{"users.json": tt.users})
+		run(t, s, "roles")
+		err := s.View(func(tx storage.Tx) error {
+			users, err := tx.Users()
+			if err != nil {
+				return err
+			}
+			for id, role := range tt.want {
+				if users[id].Role != role {
+					t.Errorf("%s: user %s has the role %q, want %q", tt.name, id, users[id].Role, role)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := run(t, s, "roles"); len(changes) != 0 {
+			t.Errorf("%s: the second run reported %q", tt.name, changes)
+		}
+	}
+}
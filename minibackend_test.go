@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The `testCategory` constant is the ID of the category every test board starts with, so that tasks
+// can be created without setting one up first.
+const testCategory = "cat-test"
+
+// The `newTestBoard` function points the server at a fresh JSON data directory and opens its default
+// board the way `main` does, with the write hooks installed and authentication switched off, so that
+// handlers can be called directly. The global state is restored when the test ends.
+func newTestBoard(t *testing.T) *board {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := storage.OpenJSON(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.PutCategory(&structures.Category{ID_category: testCategory, Name: "Test"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldStore, oldConfig, oldHooks, oldAuth := store, boardConfig, writeHooks, authEnabled
+	store = s
+	boardConfig = storage.Config{Backend: "json", DataDir: dir}
+	writeHooks = []func(b *board, changes []change){indexChanges, publishChanges}
+	authEnabled = false
+
+	b, err := newBoard(defaultBoardID, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openBoardsMu.Lock()
+	oldBoards := openBoards
+	openBoards = map[string]*board{defaultBoardID: b}
+	openBoardsMu.Unlock()
+
+	t.Cleanup(func() {
+		openBoardsMu.Lock()
+		openBoards = oldBoards
+		openBoardsMu.Unlock()
+		store, boardConfig, writeHooks, authEnabled = oldStore, oldConfig, oldHooks, oldAuth
+	})
+	return b
+}
+
+// The `serve` function calls `handler` with a request for `method` and `target` and the JSON `body`.
+// `pathValues` are pairs of wildcard names and values, as the mux would set them.
+func serve(handler http.HandlerFunc, method, target, body string, pathValues ...string) *httptest.ResponseRecorder {
+	return serveWith(handler, method, target, body, "application/json", pathValues...)
+}
+
+// The `serveWith` function is `serve` with a body of the given content type.
+func serveWith(handler http.HandlerFunc, method, target, body, contentType string, pathValues ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	for i := 0; i+1 < len(pathValues); i += 2 {
+		r.SetPathValue(pathValues[i], pathValues[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// The `countEntities` function returns the number of tasks and contacts stored on the board `b`.
+func countEntities(t *testing.T, b *board) (tasks, contacts int) {
+	t.Helper()
+	err := b.store.View(func(tx storage.Tx) error {
+		allTasks, err := tx.Tasks()
+		if err != nil {
+			return err
+		}
+		allContacts, err := tx.Contacts()
+		tasks, contacts = len(allTasks), len(allContacts)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tasks, contacts
+}
+
+// The `readTask` function returns the task `id` as it is stored on the board `b`.
+func readTask(t *testing.T, b *board, id string) *structures.Task {
+	t.Helper()
+	var task *structures.Task
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		task, err = tx.Task(id)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("reading task %s: %v", id, err)
+	}
+	return task
+}
+
+// The `taskJSON` function returns the body of a valid task with the given title.
+func taskJSON(title string) string {
+	return fmt.Sprintf(`{"title": %q, "status": "ToDo", "prio": "Low", "category": %q}`, title, testCategory)
+}
+
+// The `contactJSON` function returns the body of a valid contact with the given first name.
+func contactJSON(name string) string {
+	return fmt.Sprintf(`{"first_name": %q, "email": "%s@example.com"}`, name, strings.ToLower(name))
+}
+
+// The `TestParallelWritesAreNotLost` test creates tasks and contacts and removes contacts from many
+// goroutines at once. Every read-modify-write cycle on the data files has to be serialized, so in the
+// end the store holds exactly the tasks and contacts that were created and not removed. Run it with
+// `go test -race` to also catch unsynchronized memory access.
+func TestParallelWritesAreNotLost(t *testing.T) {
+	b := newTestBoard(t)
+	const n = 20
+
+	var doomed []string
+	for i := 0; i < n; i++ {
+		c := &structures.Contact{First_Name: fmt.Sprintf("Doomed%d", i), Email: fmt.Sprintf("doomed%d@example.com", i)}
+		if err := insertContact(b, c); err != nil {
+			t.Fatal(err)
+		}
+		doomed = append(doomed, c.ID_contact)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 3*n)
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			if w := serve(createTask, "POST", "/tasks", taskJSON(fmt.Sprintf("Task %d", i))); w.Code != http.StatusCreated {
+				errs <- fmt.Sprintf("POST /tasks: %d %s", w.Code, w.Body)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if w := serve(add_contact, "POST", "/add_contact", contactJSON(fmt.Sprintf("Added%d", i))); w.Code != http.StatusCreated {
+				errs <- fmt.Sprintf("/add_contact: %d %s", w.Code, w.Body)
+			}
+		}(i)
+		go func(id string) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"ID_contact": %q}`, id)
+			if w := serve(removeContact, "DELETE", "/remove_contact", body); w.Code != http.StatusOK {
+				errs <- fmt.Sprintf("/remove_contact: %d %s", w.Code, w.Body)
+			}
+		}(doomed[i])
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+
+	var tasks map[string]*structures.Task
+	var contacts map[string]*structures.Contact
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		if tasks, err = tx.Tasks(); err != nil {
+			return err
+		}
+		contacts, err = tx.Contacts()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != n {
+		t.Errorf("got %d tasks, want %d", len(tasks), n)
+	}
+	names := map[string]bool{}
+	for _, c := range contacts {
+		names[c.First_Name] = true
+	}
+	for i := 0; i < n; i++ {
+		if !names[fmt.Sprintf("Added%d", i)] {
+			t.Errorf("contact Added%d was lost", i)
+		}
+		if names[fmt.Sprintf("Doomed%d", i)] {
+			t.Errorf("contact Doomed%d was not removed", i)
+		}
+	}
+	if len(contacts) != n {
+		t.Errorf("got %d contacts, want %d", len(contacts), n)
+	}
+
+	// The files on disk must agree with what the store returned, so the data directory is read once
+	// more directly.
+	data, err := os.ReadFile(filepath.Join(boardConfig.DataDir, "tasks.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var onDisk map[string]json.RawMessage
+	if err := json.Unmarshal(data, &onDisk); err != nil {
+		t.Fatal(err)
+	}
+	if len(onDisk) != n {
+		t.Errorf("tasks.json holds %d tasks, want %d", len(onDisk), n)
+	}
+}
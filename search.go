@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"minibackend/search"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"strconv"
+)
+
+// The `searchResults` struct is the response of `GET /search`: the matching tasks and contacts, each
+// group ordered from the best to the worst match.
+type searchResults struct {
+	Query    string          `json:"query"`
+	Tasks    []taskResult    `json:"tasks"`
+	Contacts []contactResult `json:"contacts"`
+}
+
+type taskResult struct {
+	Score float64          `json:"score"`
+	Task  *structures.Task `json:"task"`
+}
+
+type contactResult struct {
+	Score   float64             `json:"score"`
+	Contact *structures.Contact `json:"contact"`
+}
+
+// The `searchHandler` function handles `GET /search?q=...`. It looks the words of `q` up in the
+// search index and serves the matching tasks and contacts, grouped by type and ranked by relevance.
+// `limit` caps the number of results per group (default 20).
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	q := r.URL.Query().Get("q")
+	if len(search.Tokenize(q)) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_query", "q: the search needs at least one word")
+		return
+	}
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > MaxPageSize {
+			writeError(w, http.StatusBadRequest, "invalid_query", fmt.Sprintf("limit: must be a number between 1 and %d", MaxPageSize))
+			return
+		}
+	}
+
+	// The hits only carry IDs, so the entities are loaded from the store. A hit whose entity is gone,
+	// because it was deleted after the lookup, is skipped.
+	results := searchResults{Query: q, Tasks: []taskResult{}, Contacts: []contactResult{}}
+	hits := b.index.Search(q)
+	err := b.store.View(func(tx storage.Tx) error {
+		for _, hit := range hits {
+			switch {
+			case hit.Kind == kindTask && len(results.Tasks) < limit:
+				t, err := tx.Task(hit.ID)
+				if errors.Is(err, storage.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				results.Tasks = append(results.Tasks, taskResult{hit.Score, t})
+			case hit.Kind == kindContact && len(results.Contacts) < limit:
+				c, err := tx.Contact(hit.ID)
+				if errors.Is(err, storage.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				results.Contacts = append(results.Contacts, contactResult{hit.Score, c})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// The `buildSearchIndex` function indexes every task and contact of the board `b`. It is called once
+// when the board is opened; from then on `indexChanges` keeps the index current.
+func buildSearchIndex(b *board) error {
+	return b.store.View(func(tx storage.Tx) error {
+		allTasks, err := tx.Tasks()
+		if err != nil {
+			return err
+		}
+		for _, t := range allTasks {
+			indexTask(b.index, t)
+		}
+		allContacts, err := tx.Contacts()
+		if err != nil {
+			return err
+		}
+		for _, c := range allContacts {
+			indexContact(b.index, c)
+		}
+		return nil
+	})
+}
+
+// The `indexChanges` function is a write hook that applies the changes of a committed transaction to
+// the search index of the board.
+func indexChanges(b *board, changes []change) {
+	for _, ch := range changes {
+		switch {
+		case ch.Task != nil:
+			indexTask(b.index, ch.Task)
+		case ch.Contact != nil:
+			indexContact(b.index, ch.Contact)
+		case ch.Kind == kindTask || ch.Kind == kindContact:
+			b.index.Remove(ch.Kind, ch.ID)
+		}
+	}
+}
+
+// The `indexTask` function indexes the title, description and subtask titles of `t` in `index`. The
+// title weighs most, since it is what users remember a card by.
+func indexTask(index *search.Index, t *structures.Task) {
+	fields := []search.Field{{Text: t.Title, Weight: 3}, {Text: t.Description, Weight: 1}}
+	for _, sub := range t.Subtasks {
+		fields = append(fields, search.Field{Text: sub.Title, Weight: 2})
+	}
+	index.Put(kindTask, t.ID_task, fields...)
+}
+
+// The `indexContact` function indexes the name and email address of `c` in `index`.
+func indexContact(index *search.Index, c *structures.Contact) {
+	index.Put(kindContact, c.ID_contact,
+		search.Field{Text: c.First_Name + " " + c.Last_Name, Weight: 3},
+		search.Field{Text: c.Email, Weight: 2},
+	)
+}
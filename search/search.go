@@ -0,0 +1,166 @@
+// Package search implements the in-process full-text index behind `GET /search`. Documents are
+// identified by a kind ("task", "contact") and an ID and consist of weighted text fields; the index
+// maps every word of those fields to the documents containing it, so a query only looks at the
+// documents that share at least one word with it.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The `Field` struct is one text of a document. Matches in a field with a higher `Weight` rank the
+// document higher, e.g. a word in the title counts more than the same word in the description.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// The `Hit` struct is a document found by `Search`, with its relevance score.
+type Hit struct {
+	Kind  string  `json:"kind"`
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// The `docKey` struct identifies a document in the index.
+type docKey struct {
+	kind string
+	id   string
+}
+
+// The `Index` struct is an inverted index. `postings` maps every term to the weighted number of its
+// occurrences per document, `terms` remembers the terms of each document so that it can be removed
+// again. An `Index` is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[docKey]float64
+	terms    map[docKey][]string
+}
+
+// The `New` function returns an empty index.
+func New() *Index {
+	return &Index{
+		postings: make(map[string]map[docKey]float64),
+		terms:    make(map[docKey][]string),
+	}
+}
+
+// The `Put` method indexes the document `kind`/`id` with the given fields, replacing any earlier
+// version of it.
+func (ix *Index) Put(kind, id string, fields ...Field) {
+	key := docKey{kind, id}
+	weights := make(map[string]float64)
+	for _, f := range fields {
+		for _, term := range Tokenize(f.Text) {
+			weights[term] += f.Weight
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(key)
+	for term, w := range weights {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[docKey]float64)
+		}
+		ix.postings[term][key] = w
+		ix.terms[key] = append(ix.terms[key], term)
+	}
+}
+
+// The `Remove` method drops the document `kind`/`id` from the index. Removing a document that is not
+// indexed does nothing.
+func (ix *Index) Remove(kind, id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(docKey{kind, id})
+}
+
+func (ix *Index) remove(key docKey) {
+	for _, term := range ix.terms[key] {
+		delete(ix.postings[term], key)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.terms, key)
+}
+
+// The `Search` method returns the documents that contain every word of `query`, best match first.
+// The last word also matches as a prefix, so results appear while the user is still typing. The score
+// of a document adds up the field weights of the matched words, scaled by how rare each word is, and
+// counts prefix matches at half weight.
+func (ix *Index) Search(query string) []Hit {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var scores map[docKey]float64
+	for i, word := range words {
+		// A word is looked up in the postings directly. Only the last word, which may still be
+		// incomplete, is also compared with every term of the index to find the terms it starts.
+		wordScores := make(map[docKey]float64)
+		ix.score(wordScores, ix.postings[word], 1)
+		if i == len(words)-1 {
+			for term, docs := range ix.postings {
+				if term != word && strings.HasPrefix(term, word) {
+					ix.score(wordScores, docs, 0.5)
+				}
+			}
+		}
+
+		// Every word has to match, so the candidates of the first word are narrowed down by each
+		// following one.
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		for key := range scores {
+			if s, ok := wordScores[key]; ok {
+				scores[key] += s
+			} else {
+				delete(scores, key)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for key, score := range scores {
+		hits = append(hits, Hit{Kind: key.kind, ID: key.id, Score: math.Round(score*1000) / 1000})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// The `score` method records the matches of one term, found in `docs`, in `scores`. A document keeps
+// its best match, weighted by `factor` and by how rare the term is.
+func (ix *Index) score(scores map[docKey]float64, docs map[docKey]float64, factor float64) {
+	if len(docs) == 0 {
+		return
+	}
+	idf := math.Log(1 + float64(len(ix.terms))/float64(len(docs)))
+	for key, w := range docs {
+		scores[key] = math.Max(scores[key], factor*w*idf)
+	}
+}
+
+// The `Tokenize` function splits `text` into lower-case words at every character that is neither a
+// letter nor a digit, so "E-Mail" and "e-mail" both become "e" and "mail".
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+// The `ids` function lists the documents of `hits` as "kind/id", in order.
+func ids(hits []Hit) []string {
+	var list []string
+	for _, h := range hits {
+		list = append(list, h.Kind+"/"+h.ID)
+	}
+	return list
+}
+
+// The `TestSearch` test covers exact matches of every word, prefix matches of the last word only, the
+// ranking by field weight, and replacing and removing documents.
+func TestSearch(t *testing.T) {
+	ix := New()
+	ix.Put("task", "t1", Field{Text: "Write the login page", Weight: 3}, Field{Text: "OAuth and passwords", Weight: 1})
+	ix.Put("task", "t2", Field{Text: "Logging", Weight: 3}, Field{Text: "Write logs to a file", Weight: 1})
+	ix.Put("contact", "c1", Field{Text: "Ada Lovelace", Weight: 3}, Field{Text: "ada@example.com", Weight: 2})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"login", "[task/t1]"},
+		{"LOGIN page", "[task/t1]"},
+		{"log", "[task/t1 task/t2]"},
+		{"write log", "[task/t1 task/t2]"},
+		{"log write", "[]"},
+		{"lo page", "[]"},
+		{"ada", "[contact/c1]"},
+		{"example com", "[contact/c1]"},
+		{"nothing", "[]"},
+		{"", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(ids(ix.Search(tt.query))); got != tt.want {
+			t.Errorf("Search(%q): got %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	ix.Put("task", "t1", Field{Text: "Signup page", Weight: 3})
+	if got := fmt.Sprint(ids(ix.Search("login"))); got != "[]" {
+		t.Errorf("after replacing t1: got %s", got)
+	}
+	ix.Remove("task", "t2")
+	if got := fmt.Sprint(ids(ix.Search("log"))); got != "[]" {
+		t.Errorf("after removing t2: got %s", got)
+	}
+	if len(ix.postings) != len(Tokenize("Signup page Ada Lovelace ada@example.com"))-1 {
+		t.Errorf("postings of removed documents are left behind: %v", ix.postings)
+	}
+}
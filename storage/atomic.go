@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The `BackupCount` variable controls how many previous versions of every data file are kept in the
+// `backups` directory next to it. A value of zero disables backups.
+var BackupCount = 5
+
+// The `WriteFile` function replaces the file at `path` with `data` in a crash-safe way. The data is
+// first written and fsynced to a temporary file in the same directory, the current version of the
+// file is rotated into the backup set, and only then the temporary file is renamed over `path`. A
+// crash at any point therefore leaves either the old or the new content in place, never a truncated
+// file.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	// The temporary file is created in the target directory because a rename is only atomic within a
+	// single file system. If anything goes wrong before the rename it is removed again.
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// The current content is preserved in the backup set before it gets replaced. A failing backup
+	// aborts the write, so that the server never silently runs without the safety net.
+	if err := rotateBackups(path); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	tmp = nil
+
+	// The directory is synced as well so that the rename itself survives a power loss.
+	return syncDir(dir)
+}
+
+// The `backupPath` function returns the path of the `n`th backup of the file at `path`, where 1 is the
+// most recent one.
+func backupPath(path string, n int) string {
+	return filepath.Join(filepath.Dir(path), "backups", fmt.Sprintf("%s.%d", filepath.Base(path), n))
+}
+
+// The `rotateBackups` function shifts the existing backups of `path` by one position, dropping the
+// oldest, and stores the current content of `path` as backup number 1. Files that do not contain valid
+// JSON are not backed up, so a corrupt file can never push the last good copies out of the set.
+func rotateBackups(path string) error {
+	if BackupCount <= 0 {
+		return nil
+	}
+
+	current, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !json.Valid(current) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(backupPath(path, 1)), 0755); err != nil {
+		return err
+	}
+
+	// The backups are shifted from the oldest to the newest so that no file is overwritten before it
+	// has been moved on.
+	for n := BackupCount - 1; n >= 1; n-- {
+		err := os.Rename(backupPath(path, n), backupPath(path, n+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	// A hard link is the cheapest way to keep the current content, since the rename in `WriteFile`
+	// only replaces the directory entry. File systems without hard links get a plain copy.
+	if err := os.Link(path, backupPath(path, 1)); err != nil {
+		return os.WriteFile(backupPath(path, 1), current, 0644)
+	}
+	return nil
+}
+
+// The `Recover` function checks the data file at `path` on startup. If it does not contain valid
+// JSON, the most recent valid copy from the backup set is restored and the corrupt file is kept next
+// to it with a `.corrupt-<timestamp>` suffix for inspection; without a valid backup the corrupt file is
+// reported as an error. A missing file is left alone, since deleting a data file is how an operator
+// resets it. The caller has to hold the lock on `path`.
+func Recover(path string) (restored bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if json.Valid(data) {
+		return false, nil
+	}
+
+	// The backups are searched from the newest to the oldest for the first one holding valid JSON.
+	var good []byte
+	for n := 1; n <= BackupCount; n++ {
+		backup, err := os.ReadFile(backupPath(path, n))
+		if err == nil && json.Valid(backup) {
+			good = backup
+			break
+		}
+	}
+	if good == nil {
+		return false, fmt.Errorf("%s is corrupt and no valid backup is available", path)
+	}
+
+	corrupt := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	if err := os.Rename(path, corrupt); err != nil {
+		return false, err
+	}
+	if err := WriteFile(path, good, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The `writeVersions` function writes every one of `versions` to `path` with `WriteFile`, in order.
+func writeVersions(t *testing.T, path string, versions ...string) {
+	t.Helper()
+	for _, v := range versions {
+		if err := WriteFile(path, []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// The `readFile` function returns the content of `path`, or "<missing>" if there is no such file.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "<missing>"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBackupsRotate(t *testing.T) {
+	old := BackupCount
+	BackupCount = 3
+	defer func() { BackupCount = old }()
+
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	writeVersions(t, path, `{"v":1}`, `{"v":2}`, `{"v":3}`, `{"v":4}`, `{"v":5}`, `{"v":6}`)
+
+	want := []string{`{"v":5}`, `{"v":4}`, `{"v":3}`, "<missing>"}
+	for i, w := range want {
+		if got := readFile(t, backupPath(path, i+1)); got != w {
+			t.Errorf("backup %d: got %s, want %s", i+1, got, w)
+		}
+	}
+	if got := readFile(t, path); got != `{"v":6}` {
+		t.Errorf("got %s, want the last version", got)
+	}
+}
+
+// The `TestRecoverCorruptFile` test corrupts a data file and the newest of its backups. The newest
+// valid backup must be restored, and the corrupt file kept for inspection.
+func TestRecoverCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, tasksFileName)
+	writeVersions(t, path, `{"v":1}`, `{"v":2}`, `{"v":3}`)
+	if err := os.WriteFile(path, []byte(`{"v":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backupPath(path, 1), []byte(`{"v"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening the store runs the recovery.
+	if _, err := OpenJSON(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != `{"v":1}` {
+		t.Errorf("restored %s, want the newest valid backup", got)
+	}
+	corrupt, err := filepath.Glob(path + ".corrupt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupt) != 1 || readFile(t, corrupt[0]) != `{"v":` {
+		t.Errorf("got corrupt copies %v, want the corrupt file", corrupt)
+	}
+}
+
+func TestRecoverWithoutValidBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), tasksFileName)
+	writeVersions(t, path, `{"v":1}`, `{"v":2}`)
+	for _, p := range []string{path, backupPath(path, 1)} {
+		if err := os.WriteFile(p, []byte("not json"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restored, err := Recover(path)
+	if err == nil || !strings.Contains(err.Error(), "no valid backup") {
+		t.Errorf("got %v, %v, want an error", restored, err)
+	}
+	if got := readFile(t, path); got != "not json" {
+		t.Errorf("the corrupt file was changed to %s", got)
+	}
+}
+
+// The `TestRecoverLeavesMissingFile` test deletes a data file that has backups. Deleting resets the
+// file, so it must not come back.
+func TestRecoverLeavesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), tasksFileName)
+	writeVersions(t, path, `{"v":1}`, `{"v":2}`)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := Recover(path)
+	if err != nil || restored {
+		t.Errorf("got %v, %v, want nothing restored", restored, err)
+	}
+	if got := readFile(t, path); got != "<missing>" {
+		t.Errorf("the deleted file came back as %s", got)
+	}
+}
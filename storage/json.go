@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"minibackend/structures"
+	"os"
+	"path/filepath"
+)
+
+// The file names used by the JSON-file backend inside its data directory.
+const (
+	contactsFileName   = "contacts.json"
+	tasksFileName      = "tasks.json"
+	categoriesFileName = "categories.json"
+	usersFileName      = "users.json"
+	sessionsFileName   = "sessions.json"
+	boardsFileName     = "boards.json"
+	apiKeysFileName    = "apikeys.json"
+)
+
+// The `jsonStore` struct is the original storage of the server: one JSON object per collection,
+// keyed by ID, in contacts.json, tasks.json and categories.json, plus the accounts in users.json and
+// sessions.json, the board registry in boards.json and the API keys in apikeys.json.
+type jsonStore struct {
+	contactsFile   string
+	tasksFile      string
+	categoriesFile string
+	usersFile      string
+	sessionsFile   string
+	boardsFile     string
+	apiKeysFile    string
+}
+
+// The `OpenJSON` function opens the JSON-file backend on the data directory `dir`. Every data file is
+// checked for corruption first and restored from its most recent valid backup if needed.
+func OpenJSON(dir string) (Store, error) {
+	s := &jsonStore{
+		contactsFile:   filepath.Join(dir, contactsFileName),
+		tasksFile:      filepath.Join(dir, tasksFileName),
+		categoriesFile: filepath.Join(dir, categoriesFileName),
+		usersFile:      filepath.Join(dir, usersFileName),
+		sessionsFile:   filepath.Join(dir, sessionsFileName),
+		boardsFile:     filepath.Join(dir, boardsFileName),
+		apiKeysFile:    filepath.Join(dir, apiKeysFileName),
+	}
+	// The files are locked like for a commit, so that another process working on the same directory
+	// cannot write a file while it is being checked or restored.
+	err := WithLock(func() error {
+		for _, path := range s.files() {
+			restored, err := Recover(path)
+			if err != nil {
+				return err
+			}
+			if restored {
+				log.Printf("storage: restored %s from backup", path)
+			}
+		}
+		return nil
+	}, s.files()...)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *jsonStore) files() []string {
+	return []string{s.contactsFile, s.tasksFile, s.categoriesFile, s.usersFile, s.sessionsFile, s.boardsFile, s.apiKeysFile}
+}
+
+// The `newTx` method returns a transaction that reads each data file on first use. The caller has to
+// hold the lock on the files for as long as the transaction runs: each file is replaced atomically by
+// `WriteFile`, but a commit replaces them one after the other, so an unlocked reader could see some
+// collections before and others after the commit.
+func (s *jsonStore) newTx() *mapTx {
+	tx := newMapTx()
+	tx.load = s.load
+	return tx
+}
+
+// The `load` method reads the data file of the collection `c` into `tx`.
+//
+// The map key is the authoritative ID of every entity. Older versions of the server could store an
+// entity whose ID field disagrees with its key, so the field is corrected here to keep `Put` (which
+// stores under the ID field) from creating a second entry.
+func (s *jsonStore) load(tx *mapTx, c collection) error {
+	switch c {
+	case contactsCollection:
+		if err := readJSON(s.contactsFile, &tx.contacts); err != nil {
+			return err
+		}
+		for id, contact := range tx.contacts {
+			contact.ID_contact = id
+		}
+	case tasksCollection:
+		// Tasks are decoded leniently, so that a status or priority written by an older version of the
+		// server does not make the whole file unreadable.
+		var tasks map[string]json.RawMessage
+		if err := readJSON(s.tasksFile, &tasks); err != nil {
+			return err
+		}
+		for id, data := range tasks {
+			t := &structures.Task{}
+			if err := structures.UnmarshalStoredTask(data, t); err != nil {
+				return fmt.Errorf("%s: task %q: %w", s.tasksFile, id, err)
+			}
+			t.ID_task = id
+			tx.tasks[id] = t
+		}
+	case categoriesCollection:
+		var categories map[string]string
+		if err := readJSON(s.categoriesFile, &categories); err != nil {
+			return err
+		}
+		for id, name := range categories {
+			tx.categories[id] = &structures.Category{ID_category: id, Name: name}
+		}
+	case usersCollection:
+		if err := readJSON(s.usersFile, &tx.users); err != nil {
+			return err
+		}
+		for id, u := range tx.users {
+			u.ID_user = id
+		}
+	case sessionsCollection:
+		if err := readJSON(s.sessionsFile, &tx.sessions); err != nil {
+			return err
+		}
+		for hash, session := range tx.sessions {
+			session.TokenHash = hash
+		}
+	case boardsCollection:
+		if err := readJSON(s.boardsFile, &tx.boards); err != nil {
+			return err
+		}
+		for id, b := range tx.boards {
+			b.ID_board = id
+		}
+	case apiKeysCollection:
+		if err := readJSON(s.apiKeysFile, &tx.apiKeys); err != nil {
+			return err
+		}
+		for id, k := range tx.apiKeys {
+			k.ID_key = id
+		}
+	}
+	return nil
+}
+
+// The `View` method holds the shared lock on the data files while `fn` runs, so that the transaction
+// sees either all or none of the files written by a concurrent commit, whichever of them it reads.
+// Readers do not block each other, and only the files `fn` actually uses are read.
+func (s *jsonStore) View(fn func(tx Tx) error) error {
+	return WithRLock(func() error {
+		tx := s.newTx()
+		tx.readOnly = true
+		return fn(tx)
+	}, s.files()...)
+}
+
+// The `Update` method holds the lock on all data files for the whole read-modify-write cycle,
+// so that concurrent updates from this or another process never overwrite each other. Only the
+// collections changed by `fn` are written back.
+func (s *jsonStore) Update(fn func(tx Tx) error) error {
+	return WithLock(func() error {
+		tx := s.newTx()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return s.commit(tx)
+	}, s.files()...)
+}
+
+// The `commit` method writes the dirty collections of `tx`. Each file is replaced atomically; if a
+// later file fails, the files already written are put back to their previous content so that the
+// collections never disagree with each other.
+func (s *jsonStore) commit(tx *mapTx) error {
+	type pending struct {
+		path  string
+		value any
+	}
+	var writes []pending
+	if tx.tasksDirty {
+		writes = append(writes, pending{s.tasksFile, tx.tasks})
+	}
+	if tx.contactsDirty {
+		writes = append(writes, pending{s.contactsFile, tx.contacts})
+	}
+	if tx.categoriesDirty {
+		writes = append(writes, pending{s.categoriesFile, categoryNames(tx.categories)})
+	}
+	if tx.usersDirty {
+		writes = append(writes, pending{s.usersFile, tx.users})
+	}
+	if tx.sessionsDirty {
+		writes = append(writes, pending{s.sessionsFile, tx.sessions})
+	}
+	if tx.boardsDirty {
+		writes = append(writes, pending{s.boardsFile, tx.boards})
+	}
+	if tx.apiKeysDirty {
+		writes = append(writes, pending{s.apiKeysFile, tx.apiKeys})
+	}
+
+	var written []string
+	previous := map[string][]byte{}
+	for _, w := range writes {
+		old, err := os.ReadFile(w.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		previous[w.path] = old
+
+		if err := writeJSON(w.path, w.value); err != nil {
+			for _, path := range written {
+				if previous[path] == nil {
+					os.Remove(path)
+				} else {
+					WriteFile(path, previous[path], 0644)
+				}
+			}
+			return err
+		}
+		written = append(written, w.path)
+	}
+	return nil
+}
+
+func (s *jsonStore) Close() error { return nil }
+
+// The `categoryNames` function converts `categories` into the plain ID-to-name object kept in
+// categories.json.
+func categoryNames(categories map[string]*structures.Category) map[string]string {
+	names := make(map[string]string, len(categories))
+	for id, c := range categories {
+		names[id] = c.Name
+	}
+	return names
+}
+
+// The `readJSON` function decodes the JSON file at `path` into `v`. A missing file leaves `v`
+// untouched, so a data directory without e.g. categories.json simply starts empty.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// The `writeJSON` function encodes `v` with the three-space indentation used by the files in ./data
+// and writes it atomically to `path`.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "   ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data, 0644)
+}
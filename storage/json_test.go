@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"fmt"
+	"minibackend/structures"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// The `TestJSONViewSeesConsistentSnapshot` test commits a task and a contact with the same version in
+// every transaction while other goroutines read both. A reader must never see the task of one commit
+// next to the contact of another, although the two live in different files.
+func TestJSONViewSeesConsistentSnapshot(t *testing.T) {
+	s, err := OpenJSON(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const commits = 50
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for v := int64(1); v <= commits; v++ {
+			err := s.Update(func(tx Tx) error {
+				if err := tx.PutTask(&structures.Task{ID_task: "t", Version: v}); err != nil {
+					return err
+				}
+				return tx.PutContact(&structures.Contact{ID_contact: "c", Version: v})
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				err := s.View(func(tx Tx) error {
+					task, taskErr := tx.Task("t")
+					contact, contactErr := tx.Contact("c")
+					if taskErr != nil || contactErr != nil {
+						if taskErr != contactErr {
+							return fmt.Errorf("task: %v, contact: %v", taskErr, contactErr)
+						}
+						return nil
+					}
+					if task.Version != contact.Version {
+						return fmt.Errorf("task at version %d, contact at version %d", task.Version, contact.Version)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// The `TestJSONReadsOnlyUsedFiles` test breaks tasks.json and checks that transactions which do not
+// touch the tasks still work, while reading the tasks reports the broken file.
+func TestJSONReadsOnlyUsedFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenJSON(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, tasksFileName), []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Update(func(tx Tx) error {
+		return tx.PutSession(&structures.Session{TokenHash: "h", User: "usr1"})
+	})
+	if err != nil {
+		t.Fatalf("writing a session: %v", err)
+	}
+	err = s.View(func(tx Tx) error {
+		_, err := tx.Session("h")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("reading a session: %v", err)
+	}
+	err = s.View(func(tx Tx) error {
+		_, err := tx.Tasks()
+		return err
+	})
+	if err == nil {
+		t.Error("reading the broken tasks succeeded")
+	}
+}
+
+// The `TestJSONReadsUnknownStatus` test reads a task stored by an older server version with a status
+// and a priority that are no longer allowed. Rejecting them while loading would make every task of the
+// file unreadable.
+func TestJSONReadsUnknownStatus(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"tk1": {"ID_task": "tk1", "title": "Old", "status": "Blocked", "prio": "Whenever"}}`
+	if err := os.WriteFile(filepath.Join(dir, tasksFileName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenJSON(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.View(func(tx Tx) error {
+		task, err := tx.Task("tk1")
+		if err != nil {
+			return err
+		}
+		if task.Status != "Blocked" || task.Prio != "Whenever" {
+			t.Errorf("got status %q and prio %q", task.Status, task.Prio)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
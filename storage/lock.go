@@ -0,0 +1,119 @@
+// Package storage contains the persistence helpers shared by the HTTP handlers. It makes sure that
+// read-modify-write cycles on the JSON data files never interleave, neither between goroutines of the
+// same server nor between several processes working on the same data directory.
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// The `fileLocks` map associates the absolute path of every data file with the read-write mutex that
+// serializes access to it inside this process. Entries are created on first use and never removed,
+// which is fine because the server only works on a handful of files.
+var (
+	fileLocksMu sync.Mutex
+	fileLocks   = map[string]*sync.RWMutex{}
+)
+
+// The `processLock` function returns the in-process mutex guarding the file at `path`, creating it on
+// first use.
+func processLock(path string) *sync.RWMutex {
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+
+	mu, ok := fileLocks[path]
+	if !ok {
+		mu = &sync.RWMutex{}
+		fileLocks[path] = mu
+	}
+	return mu
+}
+
+// The `Lock` function acquires exclusive access to the data file at `path` and returns the function
+// that releases it again. Goroutines of this process are serialized through a mutex, other processes
+// through an advisory lock on a sidecar `<path>.lock` file. The sidecar is used instead of the data
+// file itself so that the lock survives the data file being replaced.
+func Lock(path string) (unlock func(), err error) {
+	return lock(path, false)
+}
+
+// The `RLock` function acquires shared access to the data file at `path`, like `Lock` but for readers:
+// any number of readers may hold it at the same time, but not while a writer holds `Lock`.
+func RLock(path string) (unlock func(), err error) {
+	return lock(path, true)
+}
+
+func lock(path string, shared bool) (unlock func(), err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The in-process mutex is taken first so that goroutines of this server queue up here instead of
+	// all blocking inside the operating system lock call.
+	mu := processLock(abs)
+	lockMu, unlockMu := mu.Lock, mu.Unlock
+	if shared {
+		lockMu, unlockMu = mu.RLock, mu.RUnlock
+	}
+	lockMu()
+
+	// The sidecar lock file is opened (and created if needed) and locked. If any of these steps fail
+	// the mutex is released again before the error is returned.
+	f, err := os.OpenFile(abs+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		unlockMu()
+		return nil, err
+	}
+	if err := lockFile(f, shared); err != nil {
+		f.Close()
+		unlockMu()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+		unlockMu()
+	}, nil
+}
+
+// The `WithLock` function runs `fn` while holding the lock on every file in `paths`. The locks are
+// always acquired in sorted order so that two callers locking overlapping sets of files can never
+// deadlock, and they are released once `fn` returns.
+func WithLock(fn func() error, paths ...string) error {
+	return withLocks(fn, false, paths)
+}
+
+// The `WithRLock` function runs `fn` while holding the shared lock on every file in `paths`, so that
+// no writer can replace any of them until `fn` returns.
+func WithRLock(fn func() error, paths ...string) error {
+	return withLocks(fn, true, paths)
+}
+
+func withLocks(fn func() error, shared bool, paths []string) error {
+	sorted := make([]string, 0, len(paths))
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		sorted = append(sorted, abs)
+	}
+	sort.Strings(sorted)
+
+	for i, p := range sorted {
+		if i > 0 && p == sorted[i-1] {
+			continue
+		}
+		unlock, err := lock(p, shared)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	return fn()
+}
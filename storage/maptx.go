@@ -0,0 +1,451 @@
+package storage
+
+import (
+	"errors"
+	"minibackend/structures"
+)
+
+// The `errReadOnly` error is returned when a write is attempted inside a `View` transaction.
+var errReadOnly = errors.New("storage: write in read-only transaction")
+
+// The `mapTx` struct implements `Tx` on top of plain maps. It is shared by the JSON-file and the
+// in-memory backend, which both load or copy their data into maps before running a transaction. The
+// dirty flags tell the backend which collections have to be written back on commit.
+//
+// A backend that sets `load` fills the collections lazily instead: every method first makes sure its
+// collection has been loaded, so a transaction that only looks up a session never reads the tasks.
+type mapTx struct {
+	load   func(tx *mapTx, c collection) error
+	loaded [collectionCount]bool
+
+	contacts   map[string]*structures.Contact
+	tasks      map[string]*structures.Task
+	categories map[string]*structures.Category
+	users      map[string]*structures.User
+	sessions   map[string]*structures.Session
+	boards     map[string]*structures.Board
+	apiKeys    map[string]*structures.APIKey
+	readOnly   bool
+
+	contactsDirty   bool
+	tasksDirty      bool
+	categoriesDirty bool
+	usersDirty      bool
+	sessionsDirty   bool
+	boardsDirty     bool
+	apiKeysDirty    bool
+}
+
+// The `collection` type names one of the collections of a `mapTx`, for loading it on first use.
+type collection int
+
+const (
+	contactsCollection collection = iota
+	tasksCollection
+	categoriesCollection
+	usersCollection
+	sessionsCollection
+	boardsCollection
+	apiKeysCollection
+	collectionCount
+)
+
+// The `need` method loads the collection `c` through `load` unless it is already there. Without a
+// `load` function every collection counts as loaded.
+func (tx *mapTx) need(c collection) error {
+	if tx.load == nil || tx.loaded[c] {
+		return nil
+	}
+	if err := tx.load(tx, c); err != nil {
+		return err
+	}
+	tx.loaded[c] = true
+	return nil
+}
+
+// The `newMapTx` function returns a transaction over empty collections.
+func newMapTx() *mapTx {
+	return &mapTx{
+		contacts:   make(map[string]*structures.Contact),
+		tasks:      make(map[string]*structures.Task),
+		categories: make(map[string]*structures.Category),
+		users:      make(map[string]*structures.User),
+		sessions:   make(map[string]*structures.Session),
+		boards:     make(map[string]*structures.Board),
+		apiKeys:    make(map[string]*structures.APIKey),
+	}
+}
+
+// The `clone` method returns a copy of the transaction state that shares no entities with `tx`.
+func (tx *mapTx) clone() *mapTx {
+	c := newMapTx()
+	for id, contact := range tx.contacts {
+		c.contacts[id] = contact.Clone()
+	}
+	for id, task := range tx.tasks {
+		c.tasks[id] = task.Clone()
+	}
+	for id, category := range tx.categories {
+		copied := *category
+		c.categories[id] = &copied
+	}
+	for id, u := range tx.users {
+		c.users[id] = u.Clone()
+	}
+	for hash, s := range tx.sessions {
+		c.sessions[hash] = s.Clone()
+	}
+	for id, b := range tx.boards {
+		c.boards[id] = b.Clone()
+	}
+	for id, k := range tx.apiKeys {
+		c.apiKeys[id] = k.Clone()
+	}
+	return c
+}
+
+func (tx *mapTx) Contacts() (map[string]*structures.Contact, error) {
+	if err := tx.need(contactsCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.Contact, len(tx.contacts))
+	for id, c := range tx.contacts {
+		all[id] = c.Clone()
+	}
+	return all, nil
+}
+
+func (tx *mapTx) Contact(id string) (*structures.Contact, error) {
+	if err := tx.need(contactsCollection); err != nil {
+		return nil, err
+	}
+	c, ok := tx.contacts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c.Clone(), nil
+}
+
+func (tx *mapTx) PutContact(c *structures.Contact) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(contactsCollection); err != nil {
+		return err
+	}
+	tx.contacts[c.ID_contact] = c.Clone()
+	tx.contactsDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteContact(id string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(contactsCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.contacts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.contacts, id)
+	tx.contactsDirty = true
+	return nil
+}
+
+func (tx *mapTx) Tasks() (map[string]*structures.Task, error) {
+	if err := tx.need(tasksCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.Task, len(tx.tasks))
+	for id, t := range tx.tasks {
+		all[id] = t.Clone()
+	}
+	return all, nil
+}
+
+func (tx *mapTx) Task(id string) (*structures.Task, error) {
+	if err := tx.need(tasksCollection); err != nil {
+		return nil, err
+	}
+	t, ok := tx.tasks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return t.Clone(), nil
+}
+
+func (tx *mapTx) PutTask(t *structures.Task) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(tasksCollection); err != nil {
+		return err
+	}
+	tx.tasks[t.ID_task] = t.Clone()
+	tx.tasksDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteTask(id string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(tasksCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.tasks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.tasks, id)
+	tx.tasksDirty = true
+	return nil
+}
+
+func (tx *mapTx) Categories() (map[string]*structures.Category, error) {
+	if err := tx.need(categoriesCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.Category, len(tx.categories))
+	for id, c := range tx.categories {
+		copied := *c
+		all[id] = &copied
+	}
+	return all, nil
+}
+
+func (tx *mapTx) Category(id string) (*structures.Category, error) {
+	if err := tx.need(categoriesCollection); err != nil {
+		return nil, err
+	}
+	c, ok := tx.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (tx *mapTx) PutCategory(c *structures.Category) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(categoriesCollection); err != nil {
+		return err
+	}
+	copied := *c
+	tx.categories[c.ID_category] = &copied
+	tx.categoriesDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteCategory(id string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(categoriesCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.categories[id]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.categories, id)
+	tx.categoriesDirty = true
+	return nil
+}
+
+func (tx *mapTx) Users() (map[string]*structures.User, error) {
+	if err := tx.need(usersCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.User, len(tx.users))
+	for id, u := range tx.users {
+		all[id] = u.Clone()
+	}
+	return all, nil
+}
+
+func (tx *mapTx) User(id string) (*structures.User, error) {
+	if err := tx.need(usersCollection); err != nil {
+		return nil, err
+	}
+	u, ok := tx.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return u.Clone(), nil
+}
+
+func (tx *mapTx) PutUser(u *structures.User) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(usersCollection); err != nil {
+		return err
+	}
+	tx.users[u.ID_user] = u.Clone()
+	tx.usersDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteUser(id string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(usersCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.users, id)
+	tx.usersDirty = true
+	return nil
+}
+
+func (tx *mapTx) Sessions() (map[string]*structures.Session, error) {
+	if err := tx.need(sessionsCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.Session, len(tx.sessions))
+	for hash, s := range tx.sessions {
+		all[hash] = s.Clone()
+	}
+	return all, nil
+}
+
+func (tx *mapTx) Session(tokenHash string) (*structures.Session, error) {
+	if err := tx.need(sessionsCollection); err != nil {
+		return nil, err
+	}
+	s, ok := tx.sessions[tokenHash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.Clone(), nil
+}
+
+func (tx *mapTx) PutSession(s *structures.Session) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(sessionsCollection); err != nil {
+		return err
+	}
+	tx.sessions[s.TokenHash] = s.Clone()
+	tx.sessionsDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteSession(tokenHash string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(sessionsCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.sessions[tokenHash]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.sessions, tokenHash)
+	tx.sessionsDirty = true
+	return nil
+}
+
+func (tx *mapTx) Boards() (map[string]*structures.Board, error) {
+	if err := tx.need(boardsCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.Board, len(tx.boards))
+	for id, b := range tx.boards {
+		all[id] = b.Clone()
+	}
+	return all, nil
+}
+
+func (tx *mapTx) Board(id string) (*structures.Board, error) {
+	if err := tx.need(boardsCollection); err != nil {
+		return nil, err
+	}
+	b, ok := tx.boards[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return b.Clone(), nil
+}
+
+func (tx *mapTx) PutBoard(b *structures.Board) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(boardsCollection); err != nil {
+		return err
+	}
+	tx.boards[b.ID_board] = b.Clone()
+	tx.boardsDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteBoard(id string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(boardsCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.boards[id]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.boards, id)
+	tx.boardsDirty = true
+	return nil
+}
+
+func (tx *mapTx) APIKeys() (map[string]*structures.APIKey, error) {
+	if err := tx.need(apiKeysCollection); err != nil {
+		return nil, err
+	}
+	all := make(map[string]*structures.APIKey, len(tx.apiKeys))
+	for id, k := range tx.apiKeys {
+		all[id] = k.Clone()
+	}
+	return all, nil
+}
+
+func (tx *mapTx) APIKey(id string) (*structures.APIKey, error) {
+	if err := tx.need(apiKeysCollection); err != nil {
+		return nil, err
+	}
+	k, ok := tx.apiKeys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return k.Clone(), nil
+}
+
+func (tx *mapTx) PutAPIKey(k *structures.APIKey) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(apiKeysCollection); err != nil {
+		return err
+	}
+	tx.apiKeys[k.ID_key] = k.Clone()
+	tx.apiKeysDirty = true
+	return nil
+}
+
+func (tx *mapTx) DeleteAPIKey(id string) error {
+	if tx.readOnly {
+		return errReadOnly
+	}
+	if err := tx.need(apiKeysCollection); err != nil {
+		return err
+	}
+	if _, ok := tx.apiKeys[id]; !ok {
+		return ErrNotFound
+	}
+	delete(tx.apiKeys, id)
+	tx.apiKeysDirty = true
+	return nil
+}
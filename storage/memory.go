@@ -0,0 +1,42 @@
+package storage
+
+import "sync"
+
+// The `memoryStore` struct keeps the whole board in memory. Nothing is persisted, which makes it the
+// backend of choice for tests and throwaway demo servers.
+type memoryStore struct {
+	mu   sync.RWMutex
+	data *mapTx
+}
+
+// The `NewMemory` function returns an empty in-memory store.
+func NewMemory() Store {
+	return &memoryStore{data: newMapTx()}
+}
+
+// The `View` method runs `fn` against the live data under a read lock. The transaction is marked
+// read-only and only hands out copies, so `fn` cannot change the store.
+func (s *memoryStore) View(fn func(tx Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx := *s.data
+	tx.readOnly = true
+	return fn(&tx)
+}
+
+// The `Update` method runs `fn` against a private copy of the data and swaps the copy in only if `fn`
+// succeeds, which gives all-or-nothing semantics without any undo log.
+func (s *memoryStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.data.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.data = tx
+	return nil
+}
+
+func (s *memoryStore) Close() error { return nil }
@@ -0,0 +1,362 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"minibackend/structures"
+
+	_ "modernc.org/sqlite"
+)
+
+// The `sqliteSchema` constant creates the tables of the SQLite backend. Contacts, tasks, users,
+// sessions, boards and API keys are stored as JSON documents next to their ID, so that new fields
+// on the structures do not require a schema migration.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS contacts (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS tasks (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS categories (id TEXT PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS users (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS sessions (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS boards (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS api_keys (id TEXT PRIMARY KEY, data TEXT NOT NULL);
+`
+
+// The `sqliteStore` struct is the embedded SQLite backend for boards that outgrow the JSON files.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// The `OpenSQLite` function opens (and if needed creates) the SQLite database at `path`. The returned
+// `empty` flag is true if the database holds no data yet, which lets the caller seed it.
+func OpenSQLite(path string) (store Store, empty bool, err error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, false, err
+	}
+
+	// SQLite allows only one writer at a time, so a single connection avoids "database is locked"
+	// errors between the connections of the pool.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, false, err
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT (SELECT COUNT(*) FROM contacts) + (SELECT COUNT(*) FROM tasks) +
+		(SELECT COUNT(*) FROM categories)`).Scan(&count)
+	if err != nil {
+		db.Close()
+		return nil, false, err
+	}
+	return &sqliteStore{db: db}, count == 0, nil
+}
+
+func (s *sqliteStore) View(fn func(tx Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(&sqliteTx{tx: tx})
+}
+
+func (s *sqliteStore) Update(fn func(tx Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(&sqliteTx{tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// The `sqliteTx` struct implements `Tx` on top of a database transaction.
+type sqliteTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqliteTx) Contacts() (map[string]*structures.Contact, error) {
+	contacts := make(map[string]*structures.Contact)
+	err := t.scanDocuments(`SELECT id, data FROM contacts`, func(id string, data []byte) error {
+		c := &structures.Contact{}
+		if err := json.Unmarshal(data, c); err != nil {
+			return err
+		}
+		contacts[id] = c
+		return nil
+	})
+	return contacts, err
+}
+
+func (t *sqliteTx) Contact(id string) (*structures.Contact, error) {
+	c := &structures.Contact{}
+	if err := t.getDocument(`SELECT data FROM contacts WHERE id = ?`, id, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *sqliteTx) PutContact(c *structures.Contact) error {
+	return t.putDocument(`INSERT OR REPLACE INTO contacts (id, data) VALUES (?, ?)`, c.ID_contact, c)
+}
+
+func (t *sqliteTx) DeleteContact(id string) error {
+	return t.delete(`DELETE FROM contacts WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) Tasks() (map[string]*structures.Task, error) {
+	tasks := make(map[string]*structures.Task)
+	err := t.scanDocuments(`SELECT id, data FROM tasks`, func(id string, data []byte) error {
+		task := &structures.Task{}
+		if err := structures.UnmarshalStoredTask(data, task); err != nil {
+			return err
+		}
+		tasks[id] = task
+		return nil
+	})
+	return tasks, err
+}
+
+func (t *sqliteTx) Task(id string) (*structures.Task, error) {
+	task := &structures.Task{}
+	if err := t.getDocument(`SELECT data FROM tasks WHERE id = ?`, id, &storedTask{task}); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// The `storedTask` type decodes a task document with `structures.UnmarshalStoredTask`, which keeps
+// statuses and priorities written by older versions of the server.
+type storedTask struct {
+	*structures.Task
+}
+
+func (s storedTask) UnmarshalJSON(data []byte) error {
+	return structures.UnmarshalStoredTask(data, s.Task)
+}
+
+func (t *sqliteTx) PutTask(task *structures.Task) error {
+	return t.putDocument(`INSERT OR REPLACE INTO tasks (id, data) VALUES (?, ?)`, task.ID_task, task)
+}
+
+func (t *sqliteTx) DeleteTask(id string) error {
+	return t.delete(`DELETE FROM tasks WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) Categories() (map[string]*structures.Category, error) {
+	categories := make(map[string]*structures.Category)
+	err := t.scanDocuments(`SELECT id, name FROM categories`, func(id string, name []byte) error {
+		categories[id] = &structures.Category{ID_category: id, Name: string(name)}
+		return nil
+	})
+	return categories, err
+}
+
+func (t *sqliteTx) Category(id string) (*structures.Category, error) {
+	c := &structures.Category{ID_category: id}
+	err := t.tx.QueryRow(`SELECT name FROM categories WHERE id = ?`, id).Scan(&c.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *sqliteTx) PutCategory(c *structures.Category) error {
+	_, err := t.tx.Exec(`INSERT OR REPLACE INTO categories (id, name) VALUES (?, ?)`, c.ID_category, c.Name)
+	return err
+}
+
+func (t *sqliteTx) DeleteCategory(id string) error {
+	return t.delete(`DELETE FROM categories WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) Users() (map[string]*structures.User, error) {
+	users := make(map[string]*structures.User)
+	err := t.scanDocuments(`SELECT id, data FROM users`, func(id string, data []byte) error {
+		u := &structures.User{}
+		if err := json.Unmarshal(data, u); err != nil {
+			return err
+		}
+		users[id] = u
+		return nil
+	})
+	return users, err
+}
+
+func (t *sqliteTx) User(id string) (*structures.User, error) {
+	u := &structures.User{}
+	if err := t.getDocument(`SELECT data FROM users WHERE id = ?`, id, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (t *sqliteTx) PutUser(u *structures.User) error {
+	return t.putDocument(`INSERT OR REPLACE INTO users (id, data) VALUES (?, ?)`, u.ID_user, u)
+}
+
+func (t *sqliteTx) DeleteUser(id string) error {
+	return t.delete(`DELETE FROM users WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) Sessions() (map[string]*structures.Session, error) {
+	sessions := make(map[string]*structures.Session)
+	err := t.scanDocuments(`SELECT id, data FROM sessions`, func(hash string, data []byte) error {
+		s := &structures.Session{}
+		if err := json.Unmarshal(data, s); err != nil {
+			return err
+		}
+		sessions[hash] = s
+		return nil
+	})
+	return sessions, err
+}
+
+func (t *sqliteTx) Session(tokenHash string) (*structures.Session, error) {
+	s := &structures.Session{}
+	if err := t.getDocument(`SELECT data FROM sessions WHERE id = ?`, tokenHash, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (t *sqliteTx) PutSession(s *structures.Session) error {
+	return t.putDocument(`INSERT OR REPLACE INTO sessions (id, data) VALUES (?, ?)`, s.TokenHash, s)
+}
+
+func (t *sqliteTx) DeleteSession(tokenHash string) error {
+	return t.delete(`DELETE FROM sessions WHERE id = ?`, tokenHash)
+}
+
+func (t *sqliteTx) Boards() (map[string]*structures.Board, error) {
+	boards := make(map[string]*structures.Board)
+	err := t.scanDocuments(`SELECT id, data FROM boards`, func(id string, data []byte) error {
+		b := &structures.Board{}
+		if err := json.Unmarshal(data, b); err != nil {
+			return err
+		}
+		boards[id] = b
+		return nil
+	})
+	return boards, err
+}
+
+func (t *sqliteTx) Board(id string) (*structures.Board, error) {
+	b := &structures.Board{}
+	if err := t.getDocument(`SELECT data FROM boards WHERE id = ?`, id, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (t *sqliteTx) PutBoard(b *structures.Board) error {
+	return t.putDocument(`INSERT OR REPLACE INTO boards (id, data) VALUES (?, ?)`, b.ID_board, b)
+}
+
+func (t *sqliteTx) DeleteBoard(id string) error {
+	return t.delete(`DELETE FROM boards WHERE id = ?`, id)
+}
+
+func (t *sqliteTx) APIKeys() (map[string]*structures.APIKey, error) {
+	keys := make(map[string]*structures.APIKey)
+	err := t.scanDocuments(`SELECT id, data FROM api_keys`, func(id string, data []byte) error {
+		k := &structures.APIKey{}
+		if err := json.Unmarshal(data, k); err != nil {
+			return err
+		}
+		keys[id] = k
+		return nil
+	})
+	return keys, err
+}
+
+func (t *sqliteTx) APIKey(id string) (*structures.APIKey, error) {
+	k := &structures.APIKey{}
+	if err := t.getDocument(`SELECT data FROM api_keys WHERE id = ?`, id, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (t *sqliteTx) PutAPIKey(k *structures.APIKey) error {
+	return t.putDocument(`INSERT OR REPLACE INTO api_keys (id, data) VALUES (?, ?)`, k.ID_key, k)
+}
+
+func (t *sqliteTx) DeleteAPIKey(id string) error {
+	return t.delete(`DELETE FROM api_keys WHERE id = ?`, id)
+}
+
+// The `scanDocuments` method runs `query`, which must select an ID and a second text column, and
+// calls `fn` for every row.
+func (t *sqliteTx) scanDocuments(query string, fn func(id string, data []byte) error) error {
+	rows, err := t.tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return err
+		}
+		if err := fn(id, data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// The `getDocument` method loads the single JSON document selected by `query` into `v`, returning
+// `ErrNotFound` if there is no such row.
+func (t *sqliteTx) getDocument(query, id string, v any) error {
+	var data []byte
+	err := t.tx.QueryRow(query, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// The `putDocument` method stores `v` as a JSON document under `id` using the upsert `query`.
+func (t *sqliteTx) putDocument(query, id string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = t.tx.Exec(query, id, string(data))
+	return err
+}
+
+// The `delete` method runs the delete `query` for `id` and reports `ErrNotFound` if no row matched.
+func (t *sqliteTx) delete(query, id string) error {
+	res, err := t.tx.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"minibackend/structures"
+	"os"
+	"path/filepath"
+)
+
+// The `ErrNotFound` error is returned by the lookup and delete methods of a `Tx` when no entity with
+// the requested ID exists.
+var ErrNotFound = errors.New("not found")
+
+// The `Tx` interface gives access to the contacts, tasks, categories, users, sessions, boards and
+// API keys of a store. All reads and writes made through one `Tx` belong to the same transaction:
+// they see a consistent snapshot, and the writes of an `Update` transaction are either all applied
+// or not at all. Entities returned by a `Tx` are copies, so modifying them has no effect until they
+// are passed to the matching `Put` method.
+type Tx interface {
+	Contacts() (map[string]*structures.Contact, error)
+	Contact(id string) (*structures.Contact, error)
+	PutContact(c *structures.Contact) error
+	DeleteContact(id string) error
+
+	Tasks() (map[string]*structures.Task, error)
+	Task(id string) (*structures.Task, error)
+	PutTask(t *structures.Task) error
+	DeleteTask(id string) error
+
+	Categories() (map[string]*structures.Category, error)
+	Category(id string) (*structures.Category, error)
+	PutCategory(c *structures.Category) error
+	DeleteCategory(id string) error
+
+	Users() (map[string]*structures.User, error)
+	User(id string) (*structures.User, error)
+	PutUser(u *structures.User) error
+	DeleteUser(id string) error
+
+	Sessions() (map[string]*structures.Session, error)
+	Session(tokenHash string) (*structures.Session, error)
+	PutSession(s *structures.Session) error
+	DeleteSession(tokenHash string) error
+
+	Boards() (map[string]*structures.Board, error)
+	Board(id string) (*structures.Board, error)
+	PutBoard(b *structures.Board) error
+	DeleteBoard(id string) error
+
+	APIKeys() (map[string]*structures.APIKey, error)
+	APIKey(id string) (*structures.APIKey, error)
+	PutAPIKey(k *structures.APIKey) error
+	DeleteAPIKey(id string) error
+}
+
+// The `Store` interface is implemented by every storage backend. `View` runs `fn` in a read-only
+// transaction, `Update` runs it in a read-write transaction that is committed if `fn` returns nil and
+// discarded otherwise. Concurrent `Update` calls are serialized by the backend.
+type Store interface {
+	View(fn func(tx Tx) error) error
+	Update(fn func(tx Tx) error) error
+	Close() error
+}
+
+// The `Config` struct selects and configures the storage backend opened by `Open`. `Backend` is one of
+// "json", "memory" or "sqlite". `DataDir` is the directory holding the JSON data files, from
+// contacts.json to apikeys.json, and `SQLitePath` the database file used by the SQLite backend.
+type Config struct {
+	Backend    string
+	DataDir    string
+	SQLitePath string
+}
+
+// The `Open` function opens the backend selected by `cfg`. The memory backend is seeded with the JSON
+// files in `cfg.DataDir` if they exist, and so is a freshly created SQLite database, so that switching
+// backends keeps the existing board.
+func Open(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", "json":
+		return OpenJSON(cfg.DataDir)
+
+	case "memory":
+		store := NewMemory()
+		if err := seedFromJSON(store, cfg.DataDir); err != nil {
+			return nil, err
+		}
+		return store, nil
+
+	case "sqlite":
+		path := cfg.SQLitePath
+		if path == "" {
+			path = filepath.Join(cfg.DataDir, "minibackend.db")
+		}
+		store, empty, err := OpenSQLite(path)
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			if err := seedFromJSON(store, cfg.DataDir); err != nil {
+				store.Close()
+				return nil, err
+			}
+		}
+		return store, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
+
+// The `seedFromJSON` function copies the content of the JSON data directory `dir` into `dst`. A
+// missing directory is not an error, `dst` simply stays empty.
+func seedFromJSON(dst Store, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, tasksFileName)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	src, err := OpenJSON(dir)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return Copy(dst, src)
+}
+
+// The `Copy` function copies every collection of `src`, from the contacts to the API keys, into `dst`
+// within a single transaction on each side. Entities already present in `dst` are overwritten.
+func Copy(dst, src Store) error {
+	return src.View(func(from Tx) error {
+		contacts, err := from.Contacts()
+		if err != nil {
+			return err
+		}
+		tasks, err := from.Tasks()
+		if err != nil {
+			return err
+		}
+		categories, err := from.Categories()
+		if err != nil {
+			return err
+		}
+		users, err := from.Users()
+		if err != nil {
+			return err
+		}
+		sessions, err := from.Sessions()
+		if err != nil {
+			return err
+		}
+		boards, err := from.Boards()
+		if err != nil {
+			return err
+		}
+		keys, err := from.APIKeys()
+		if err != nil {
+			return err
+		}
+
+		return dst.Update(func(to Tx) error {
+			for _, c := range contacts {
+				if err := to.PutContact(c); err != nil {
+					return err
+				}
+			}
+			for _, t := range tasks {
+				if err := to.PutTask(t); err != nil {
+					return err
+				}
+			}
+			for _, c := range categories {
+				if err := to.PutCategory(c); err != nil {
+					return err
+				}
+			}
+			for _, u := range users {
+				if err := to.PutUser(u); err != nil {
+					return err
+				}
+			}
+			for _, s := range sessions {
+				if err := to.PutSession(s); err != nil {
+					return err
+				}
+			}
+			for _, b := range boards {
+				if err := to.PutBoard(b); err != nil {
+					return err
+				}
+			}
+			for _, k := range keys {
+				if err := to.PutAPIKey(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
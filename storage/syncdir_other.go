@@ -0,0 +1,6 @@
+//go:build !unix
+
+package storage
+
+// Directories cannot be opened for syncing on these platforms; the rename is durable on its own.
+func syncDir(dir string) error { return nil }
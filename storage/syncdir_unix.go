@@ -0,0 +1,15 @@
+//go:build unix
+
+package storage
+
+import "os"
+
+// The `syncDir` function flushes the directory entry changes of `dir` to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,32 @@
+package structures
+
+import "time"
+
+// The `Board` struct is a workspace with its own tasks, contacts and categories. `Members` lists the
+// IDs of the users who may see and use the board; admins may use every board. The tasks, contacts and
+// categories of a board are kept in a store of their own, so boards never see each other's data.
+type Board struct {
+	ID_board string
+	Name     string    `json:"name"`
+	Members  []string  `json:"members"`
+	Created  time.Time `json:"created"`
+}
+
+// The `Clone` method returns a copy of the board that shares no slices with `b`.
+func (b *Board) Clone() *Board {
+	clone := *b
+	if b.Members != nil {
+		clone.Members = append([]string{}, b.Members...)
+	}
+	return &clone
+}
+
+// The `HasMember` method reports whether the user `id` is a member of the board.
+func (b *Board) HasMember(id string) bool {
+	for _, m := range b.Members {
+		if m == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package structures
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The `Status` type is the board column of a task.
+type Status string
+
+// The `Priority` type is the priority of a task.
+type Priority string
+
+// The allowed statuses and priorities. The order of `Statuses` is the order of the board columns, the
+// order of `Priorities` goes from least to most pressing.
+const (
+	StatusToDo     Status = "ToDo"
+	StatusProgress Status = "Progress"
+	StatusFeedback Status = "Feedback"
+	StatusDone     Status = "Done"
+
+	PriorityLow    Priority = "Low"
+	PriorityMedium Priority = "Medium"
+	PriorityUrgent Priority = "Urgent"
+)
+
+var (
+	Statuses   = []Status{StatusToDo, StatusProgress, StatusFeedback, StatusDone}
+	Priorities = []Priority{PriorityLow, PriorityMedium, PriorityUrgent}
+)
+
+// The `ParseStatus` function returns the status named by `s`. Old clients send variants such as
+// "todo", "To Do" or "in_progress", so case, spaces, dashes and underscores are ignored, and "In
+// Progress" is accepted for "Progress". The empty string parses to the empty (unset) status.
+func ParseStatus(s string) (Status, error) {
+	key := enumKey(s)
+	if key == "inprogress" {
+		key = "progress"
+	}
+	for _, status := range Statuses {
+		if key == enumKey(string(status)) {
+			return status, nil
+		}
+	}
+	if key == "" {
+		return "", nil
+	}
+	return "", fmt.Errorf("invalid status %q: must be one of %s", s, joinEnum(Statuses))
+}
+
+// The `ParsePriority` function returns the priority named by `s`, ignoring case and surrounding
+// spaces. The empty string parses to the empty (unset) priority.
+func ParsePriority(s string) (Priority, error) {
+	key := enumKey(s)
+	for _, prio := range Priorities {
+		if key == enumKey(string(prio)) {
+			return prio, nil
+		}
+	}
+	if key == "" {
+		return "", nil
+	}
+	return "", fmt.Errorf("invalid priority %q: must be one of %s", s, joinEnum(Priorities))
+}
+
+// The `Valid` method reports whether `s` is one of the allowed statuses.
+func (s Status) Valid() bool {
+	for _, status := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// The `Valid` method reports whether `p` is one of the allowed priorities.
+func (p Priority) Valid() bool {
+	for _, prio := range Priorities {
+		if p == prio {
+			return true
+		}
+	}
+	return false
+}
+
+// The `UnmarshalJSON` method decodes a status, normalizing its spelling and rejecting unknown values.
+// Tasks read back from the data files are decoded by `UnmarshalStoredTask` instead, which keeps
+// statuses that older versions of the server allowed.
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	status, err := ParseStatus(raw)
+	if err != nil {
+		return err
+	}
+	*s = status
+	return nil
+}
+
+// The `UnmarshalJSON` method decodes a priority, normalizing its spelling and rejecting unknown values.
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	prio, err := ParsePriority(raw)
+	if err != nil {
+		return err
+	}
+	*p = prio
+	return nil
+}
+
+// The `enumKey` function reduces an enum spelling to lower case letters and digits.
+func enumKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(s)))
+}
+
+// The `joinEnum` function lists the allowed values of an enum for error messages.
+func joinEnum[T ~string](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = string(v)
+	}
+	return strings.Join(parts, ", ")
+}
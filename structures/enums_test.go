@@ -0,0 +1,89 @@
+package structures
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// The `TestStatusUnmarshalJSON` test checks that the spellings of old clients are normalized and that
+// unknown statuses are rejected.
+func TestStatusUnmarshalJSON(t *testing.T) {
+	tests := map[string]Status{
+		`"ToDo"`:        StatusToDo,
+		`"to do"`:       StatusToDo,
+		`"in_progress"`: StatusProgress,
+		`"In Progress"`: StatusProgress,
+		`"done"`:        StatusDone,
+		`""`:            "",
+	}
+	for in, want := range tests {
+		var got Status
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+		if got.Valid() != (want != "") {
+			t.Errorf("%s: Valid() = %v", in, got.Valid())
+		}
+	}
+
+	for _, in := range []string{`"Blocked"`, `42`} {
+		var s Status
+		if err := json.Unmarshal([]byte(in), &s); err == nil {
+			t.Errorf("%s decoded as the status %q", in, s)
+		}
+	}
+}
+
+// The `TestPriorityUnmarshalJSON` test is the priority counterpart of `TestStatusUnmarshalJSON`.
+func TestPriorityUnmarshalJSON(t *testing.T) {
+	tests := map[string]Priority{
+		`"Low"`:      PriorityLow,
+		`" urgent "`: PriorityUrgent,
+	}
+	for in, want := range tests {
+		var got Priority
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Errorf("%s: %v", in, err)
+		} else if got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+	}
+
+	var p Priority
+	if err := json.Unmarshal([]byte(`"Whenever"`), &p); err == nil {
+		t.Errorf("\"Whenever\" decoded as the priority %q", p)
+	}
+}
+
+// The `TestTaskWithUnknownStatus` test decodes a task as stored by an older server version, with a
+// status and a priority that are no longer allowed. A request with such a task is rejected, while the
+// data file is still readable.
+func TestTaskWithUnknownStatus(t *testing.T) {
+	data := []byte(`{"ID_task": "tk1", "status": "Blocked", "prio": "Whenever", "assigned": "cont1"}`)
+	var task Task
+	if err := json.Unmarshal(data, &task); err == nil {
+		t.Errorf("a task with the status %q and priority %q was decoded", task.Status, task.Prio)
+	}
+
+	var stored Task
+	if err := UnmarshalStoredTask(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != "Blocked" || stored.Prio != "Whenever" || !stored.LegacyAssigned() {
+		t.Errorf("got status %q, prio %q and legacy assigned %v", stored.Status, stored.Prio,
+			stored.LegacyAssigned())
+	}
+
+	// Known values are normalized on both paths.
+	data = []byte(`{"status": "in progress", "prio": "low"}`)
+	if err := UnmarshalStoredTask(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != StatusProgress || stored.Prio != PriorityLow {
+		t.Errorf("got status %q and prio %q", stored.Status, stored.Prio)
+	}
+}
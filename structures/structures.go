@@ -13,28 +13,176 @@
 // the phone number of a contact.
 package structures
 
+import "encoding/json"
+
 type Contact struct {
 	ID_contact string
 	First_Name string `json:"first_name"`
 	Last_Name  string `json:"last_name"`
 	Email      string `json:"email"`
 	Phone      string `json:"phone"`
+
+	// Version is incremented by the server on every change and used as the contact's ETag.
+	Version int64 `json:"version"`
 }
 
 type Task struct {
 	ID_task     string
-	Status      string   `json:"status"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Assigned    string   `json:"assigned"`
-	Prio        string   `json:"prio"`
-	Due_date    string   `json:"due_date"`
-	Category    string   `json:"category"`
+	Status      Status    `json:"status"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Assignees   []string  `json:"assignees"`
+	Prio        Priority  `json:"prio"`
+	Due_date    string    `json:"due_date"`
+	Category    string    `json:"category"`
 	Subtasks    []Subtask `json:"subtasks"`
+
+	// Version is incremented by the server on every change and used as the task's ETag.
+	Version int64 `json:"version"`
+
+	// legacyAssigned records that the task was decoded from the single `assigned` field of older
+	// clients and data files without an `assignees` list, see `UnmarshalJSON`.
+	legacyAssigned bool
+}
+
+// The `Category` struct is a task category. Categories are stored as a plain ID-to-name object in
+// categories.json, which is also what `GET /categories` serves to the frontend.
+type Category struct {
+	ID_category string
+	Name        string `json:"name"`
 }
 
 type Subtask struct {
-    SubtaskId int64  `json:"subtaskId"`
-    Title     string `json:"title"`
-    Checked   bool   `json:"checked"`
-}
\ No newline at end of file
+	SubtaskId int64  `json:"subtaskId"`
+	Title     string `json:"title"`
+	Checked   bool   `json:"checked"`
+}
+
+// The `Clone` method returns a copy of the contact that shares no memory with the original, so that
+// callers can modify it without affecting the copy held by a store.
+func (c *Contact) Clone() *Contact {
+	clone := *c
+	return &clone
+}
+
+// The `Clone` method returns a deep copy of the task, including its own copy of the subtasks slice.
+func (t *Task) Clone() *Task {
+	clone := *t
+	if t.Subtasks != nil {
+		clone.Subtasks = make([]Subtask, len(t.Subtasks))
+		copy(clone.Subtasks, t.Subtasks)
+	}
+	if t.Assignees != nil {
+		clone.Assignees = make([]string, len(t.Assignees))
+		copy(clone.Assignees, t.Assignees)
+	}
+	return &clone
+}
+
+// The `MarshalJSON` method encodes a task with its `assignees` and, for the Kanban frontend that still
+// reads the single `assigned` contact ID, the first assignee as `assigned` ("" if there is none). The
+// field is deprecated and will be dropped once the frontend has moved to `assignees`.
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plainTask Task
+	assigned := ""
+	if len(t.Assignees) > 0 {
+		assigned = t.Assignees[0]
+	}
+	return json.Marshal(struct {
+		plainTask
+		Assigned string `json:"assigned"`
+	}{plainTask(t), assigned})
+}
+
+// The `UnmarshalJSON` method decodes a task and keeps accepting the single `assigned` contact ID that
+// tasks had before `assignees` was introduced. A non-empty `assigned` is added in front of the
+// assignees unless it is already one of them, so old clients and old data files keep working; the
+// `assigned` written by `MarshalJSON` is always one of them and changes nothing. A missing `assignees`
+// decodes as an empty list rather than null. An unknown status or priority is rejected.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	return t.decode(data, false)
+}
+
+// The `UnmarshalStoredTask` function decodes a task read back from a data file into `t`. It works like
+// `Task.UnmarshalJSON`, except that a status or priority that is no longer allowed is kept as it is,
+// since older versions of the server stored any string. Such a task can be read, but the validation
+// only lets it be written again with an allowed value.
+func UnmarshalStoredTask(data []byte, t *Task) error {
+	return t.decode(data, true)
+}
+
+// The `decode` method implements `UnmarshalJSON` and `UnmarshalStoredTask`. The status and priority
+// are decoded as plain strings by fields that shadow those of the task, so that `lenient` decides
+// what happens to unknown values.
+func (t *Task) decode(data []byte, lenient bool) error {
+	type plainTask Task
+	aux := struct {
+		*plainTask
+		Assigned string `json:"assigned"`
+		Status   string `json:"status"`
+		Prio     string `json:"prio"`
+	}{plainTask: (*plainTask)(t), Status: string(t.Status), Prio: string(t.Prio)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	status, err := ParseStatus(aux.Status)
+	if err != nil {
+		if !lenient {
+			return err
+		}
+		status = Status(aux.Status)
+	}
+	prio, err := ParsePriority(aux.Prio)
+	if err != nil {
+		if !lenient {
+			return err
+		}
+		prio = Priority(aux.Prio)
+	}
+	t.Status, t.Prio = status, prio
+
+	t.legacyAssigned = aux.Assigned != "" && t.Assignees == nil
+	if aux.Assigned != "" && !t.HasAssignee(aux.Assigned) {
+		t.Assignees = append([]string{aux.Assigned}, t.Assignees...)
+	}
+	if t.Assignees == nil {
+		t.Assignees = []string{}
+	}
+	return nil
+}
+
+// The `LegacyAssigned` method reports whether the task was decoded from the old single `assigned`
+// field without an `assignees` list, i.e. whether its stored form still has to be rewritten by the
+// assignees migration.
+func (t *Task) LegacyAssigned() bool {
+	return t.legacyAssigned
+}
+
+// The `HasAssignee` method reports whether the contact `id` is one of the assignees of the task.
+func (t *Task) HasAssignee(id string) bool {
+	for _, a := range t.Assignees {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// The `FillSubtaskIDs` method gives every subtask without an ID, or with an ID already used by an
+// earlier subtask of the same task, a fresh ID obtained from `next`. It reports whether any ID was
+// changed.
+func (t *Task) FillSubtaskIDs(next func() int64) bool {
+	changed := false
+	seen := make(map[int64]bool, len(t.Subtasks))
+	for i := range t.Subtasks {
+		id := t.Subtasks[i].SubtaskId
+		if id == 0 || seen[id] {
+			id = next()
+			t.Subtasks[i].SubtaskId = id
+			changed = true
+		}
+		seen[id] = true
+	}
+	return changed
+}
@@ -0,0 +1,59 @@
+package structures
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// The `TestTaskAssigned` test covers the deprecated `assigned` field: tasks are encoded with their
+// first assignee as `assigned`, an old task with only `assigned` decodes into the assignees and is
+// flagged for the migration, and an encoded task decodes to the same assignees without the flag.
+func TestTaskAssigned(t *testing.T) {
+	data, err := json.Marshal(&Task{ID_task: "tk1", Assignees: []string{"c1", "c2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["assigned"] != "c1" {
+		t.Errorf("assigned: got %v, want c1", fields["assigned"])
+	}
+
+	var decoded Task
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Assignees, []string{"c1", "c2"}) || decoded.LegacyAssigned() {
+		t.Errorf("round trip: got assignees %v, legacy %v", decoded.Assignees, decoded.LegacyAssigned())
+	}
+
+	data, err = json.Marshal(&Task{ID_task: "tk2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["assigned"] != "" {
+		t.Errorf("assigned without assignees: got %v, want \"\"", fields["assigned"])
+	}
+
+	var old Task
+	if err := json.Unmarshal([]byte(`{"ID_task": "tk3", "assigned": "c3"}`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(old.Assignees, []string{"c3"}) || !old.LegacyAssigned() {
+		t.Errorf("old task: got assignees %v, legacy %v", old.Assignees, old.LegacyAssigned())
+	}
+
+	var changed Task
+	if err := json.Unmarshal([]byte(`{"ID_task": "tk4", "assigned": "c3", "assignees": ["c1"]}`), &changed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed.Assignees, []string{"c3", "c1"}) || changed.LegacyAssigned() {
+		t.Errorf("assigned changed by an old client: got assignees %v, legacy %v", changed.Assignees, changed.LegacyAssigned())
+	}
+}
@@ -0,0 +1,107 @@
+package structures
+
+import "time"
+
+// The `User` struct is an account that can log in to the board. `PasswordHash` is the bcrypt hash of
+// the password; the password itself is never stored. Handlers must not send `PasswordHash` to clients.
+type User struct {
+	ID_user      string
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	Role         Role      `json:"role"`
+	Created      time.Time `json:"created"`
+}
+
+// The `Role` type decides what a user may do on the board. Guests may only read, members may also
+// change tasks and contacts, and admins may do everything, including deleting contacts and categories
+// and managing users.
+type Role string
+
+const (
+	RoleGuest  Role = "guest"
+	RoleMember Role = "member"
+	RoleAdmin  Role = "admin"
+)
+
+// The `Roles` variable lists the roles from the least to the most privileged.
+var Roles = []Role{RoleGuest, RoleMember, RoleAdmin}
+
+// The `Valid` method reports whether `r` is one of the known roles.
+func (r Role) Valid() bool {
+	return r.rank() >= 0
+}
+
+// The `Includes` method reports whether `r` grants at least the rights of `required`. An unknown or
+// empty role includes nothing.
+func (r Role) Includes(required Role) bool {
+	return r.rank() >= 0 && r.rank() >= required.rank()
+}
+
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// The `Session` struct is a login session. It is stored under the SHA-256 hash of its token, so a
+// leaked data file does not reveal tokens that can be used to log in.
+type Session struct {
+	TokenHash string    `json:"token_hash"`
+	User      string    `json:"user"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+}
+
+// The `Clone` method returns a copy of the user.
+func (u *User) Clone() *User {
+	clone := *u
+	return &clone
+}
+
+// The `Clone` method returns a copy of the session.
+func (s *Session) Clone() *Session {
+	clone := *s
+	return &clone
+}
+
+// The `APIKey` struct is a long-lived token for scripts and integrations that cannot log in with a
+// password. A request made with the key acts as the user `User`, limited by `Scope` and, if `Board`
+// is set, to the routes of that board. Like sessions, keys are stored as the SHA-256 hash of the token
+// only; `Prefix` is the beginning of the token, shown so that users can tell their keys apart. A zero
+// `Expires` means the key never expires, and a zero `LastUsed` that it has never been used.
+type APIKey struct {
+	ID_key   string
+	Name     string    `json:"name"`
+	Prefix   string    `json:"prefix"`
+	KeyHash  string    `json:"key_hash"`
+	User     string    `json:"user"`
+	Board    string    `json:"board,omitempty"`
+	Scope    Scope     `json:"scope"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// The `Scope` type limits what an API key may do: "read" keys may only read, "write" keys may do
+// everything the role of their user allows.
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+)
+
+// The `Valid` method reports whether `s` is one of the known scopes.
+func (s Scope) Valid() bool {
+	return s == ScopeRead || s == ScopeWrite
+}
+
+// The `Clone` method returns a copy of the API key.
+func (k *APIKey) Clone() *APIKey {
+	clone := *k
+	return &clone
+}
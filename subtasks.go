@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minibackend/ids"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"strconv"
+)
+
+// The subtask handlers change a single subtask inside one store transaction, so ticking a checkbox
+// neither has to send the whole task nor overwrites edits made to other parts of the task in the
+// meantime. Every change gives the task a new version; the responses carry the new ETag of the task,
+// and an `If-Match` header makes a change conditional on it.
+
+// The `createSubtask` function handles `POST /tasks/{id}/subtasks`. The subtask from the request body
+// is appended to the task under a new server-generated ID, and the response is 201 Created with the
+// stored subtask.
+func createSubtask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var subtask structures.Subtask
+	if err := json.NewDecoder(r.Body).Decode(&subtask); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	subtask.SubtaskId = ids.NewSubtaskID()
+
+	task, err := changeSubtasks(b, r.PathValue("id"), r.Header.Get("If-Match"), func(t *structures.Task) error {
+		t.Subtasks = append(t.Subtasks, subtask)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("Location", b.path(fmt.Sprintf("/tasks/%s/subtasks/%d", task.ID_task, subtask.SubtaskId)))
+	w.Header().Set("ETag", etag(task.Version))
+	writeJSON(w, http.StatusCreated, &subtask)
+}
+
+// The `patchSubtask` function handles `PATCH /tasks/{id}/subtasks/{subtaskId}`. Only the fields
+// present in the body, `title` and `checked`, are changed.
+func patchSubtask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var patch struct {
+		Title   *string `json:"title"`
+		Checked *bool   `json:"checked"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	var subtask structures.Subtask
+	task, err := changeSubtasks(b, r.PathValue("id"), r.Header.Get("If-Match"), func(t *structures.Task) error {
+		i, err := subtaskIndex(t, r.PathValue("subtaskId"))
+		if err != nil {
+			return err
+		}
+		if patch.Title != nil {
+			t.Subtasks[i].Title = *patch.Title
+		}
+		if patch.Checked != nil {
+			t.Subtasks[i].Checked = *patch.Checked
+		}
+		subtask = t.Subtasks[i]
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("ETag", etag(task.Version))
+	writeJSON(w, http.StatusOK, &subtask)
+}
+
+// The `deleteSubtask` function handles `DELETE /tasks/{id}/subtasks/{subtaskId}` and answers with 204
+// No Content.
+func deleteSubtask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	task, err := changeSubtasks(b, r.PathValue("id"), r.Header.Get("If-Match"), func(t *structures.Task) error {
+		i, err := subtaskIndex(t, r.PathValue("subtaskId"))
+		if err != nil {
+			return err
+		}
+		t.Subtasks = append(t.Subtasks[:i], t.Subtasks[i+1:]...)
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("ETag", etag(task.Version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The `reorderSubtasks` function handles `PUT /tasks/{id}/subtasks/order`. The body is an object
+// {"order": [...]} listing the IDs of all subtasks of the task in their new order; the response is
+// the reordered list of subtasks. A list that leaves out, repeats or invents a subtask is rejected
+// with 422.
+func reorderSubtasks(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var body struct {
+		Order []int64 `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	task, err := changeSubtasks(b, r.PathValue("id"), r.Header.Get("If-Match"), func(t *structures.Task) error {
+		byID := make(map[int64]structures.Subtask, len(t.Subtasks))
+		for _, sub := range t.Subtasks {
+			byID[sub.SubtaskId] = sub
+		}
+		if len(body.Order) != len(byID) {
+			return errInvalidOrder(fmt.Sprintf("must list all %d subtasks of the task", len(byID)))
+		}
+
+		reordered := make([]structures.Subtask, 0, len(body.Order))
+		for _, id := range body.Order {
+			sub, ok := byID[id]
+			if !ok {
+				return errInvalidOrder(fmt.Sprintf("unknown or repeated subtask %d", id))
+			}
+			delete(byID, id)
+			reordered = append(reordered, sub)
+		}
+		t.Subtasks = reordered
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("ETag", etag(task.Version))
+	writeJSON(w, http.StatusOK, task.Subtasks)
+}
+
+// The `changeSubtasks` function loads the task `id`, lets `fn` change its subtasks and stores it with
+// a new version, all in one transaction. An unknown task results in a 404 error, and a non-empty
+// `ifMatch` has to match the ETag of the task. The changed task is validated like any other task
+// before it is stored.
+func changeSubtasks(b *board, id, ifMatch string, fn func(t *structures.Task) error) (*structures.Task, error) {
+	var task *structures.Task
+	err := b.update(func(tx storage.Tx) error {
+		var err error
+		task, err = tx.Task(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errTaskNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(ifMatch, true, task.Version); err != nil {
+			return err
+		}
+
+		if err := fn(task); err != nil {
+			return err
+		}
+		task.Version++
+		if err := validateTask(tx, task); err != nil {
+			return err
+		}
+		return tx.PutTask(task)
+	})
+	return task, err
+}
+
+// The `subtaskIndex` function returns the position of the subtask with the ID `subtaskID` in `t`, or
+// a 404 error if the task has no such subtask.
+func subtaskIndex(t *structures.Task, subtaskID string) (int, error) {
+	id, err := strconv.ParseInt(subtaskID, 10, 64)
+	if err == nil {
+		for i, sub := range t.Subtasks {
+			if sub.SubtaskId == id {
+				return i, nil
+			}
+		}
+	}
+	return 0, &httpError{http.StatusNotFound, "not_found",
+		fmt.Sprintf("subtask %q not found in task %q", subtaskID, t.ID_task)}
+}
+
+// The `errInvalidOrder` function returns the 422 error for an invalid subtask order.
+func errInvalidOrder(message string) error {
+	return validation.Errors{{Field: "order", Message: message}}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The `TestSubtasks` test adds, changes and removes subtasks of a task and checks that every change
+// gives the task a new version, and that unknown subtasks and stale ETags are refused.
+func TestSubtasks(t *testing.T) {
+	b := newTestBoard(t)
+	task := &structures.Task{Title: "Parent", Status: structures.StatusToDo,
+		Prio: structures.PriorityLow, Category: testCategory}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+	id := task.ID_task
+
+	var subtaskIDs []int64
+	for i, title := range []string{"First", "Second", "Third"} {
+		body := fmt.Sprintf(`{"title": %q}`, title)
+		w := serve(createSubtask, "POST", "/tasks/"+id+"/subtasks", body, "id", id)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST %s: got %d %s", title, w.Code, w.Body)
+		}
+		var sub structures.Subtask
+		if err := json.Unmarshal(w.Body.Bytes(), &sub); err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf("/tasks/%s/subtasks/%d", id, sub.SubtaskId)
+		if w.Header().Get("Location") != want {
+			t.Errorf("POST %s: got Location %q, want %q", title, w.Header().Get("Location"), want)
+		}
+		if want := etag(int64(i + 2)); w.Header().Get("ETag") != want {
+			t.Errorf("POST %s: got ETag %s, want %s", title, w.Header().Get("ETag"), want)
+		}
+		subtaskIDs = append(subtaskIDs, sub.SubtaskId)
+	}
+	if got := readTask(t, b, id); len(got.Subtasks) != 3 || got.Version != 4 {
+		t.Fatalf("got %d subtasks and version %d, want 3 and 4", len(got.Subtasks), got.Version)
+	}
+
+	first := fmt.Sprint(subtaskIDs[0])
+	w := serve(patchSubtask, "PATCH", "/tasks/"+id+"/subtasks/"+first, `{"checked": true}`,
+		"id", id, "subtaskId", first)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"checked":true`) {
+		t.Errorf("PATCH: got %d %s", w.Code, w.Body)
+	}
+
+	// A change guarded by an old ETag is refused, since the task has moved on in the meantime.
+	r := httptest.NewRequest("DELETE", "/tasks/"+id+"/subtasks/"+first, nil)
+	r.Header.Set("If-Match", etag(4))
+	r.SetPathValue("id", id)
+	r.SetPathValue("subtaskId", first)
+	stale := httptest.NewRecorder()
+	deleteSubtask(stale, r)
+	if stale.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with a stale ETag: got %d %s, want 412", stale.Code, stale.Body)
+	}
+
+	for _, subtaskID := range []string{"12345", "not-a-number"} {
+		w := serve(patchSubtask, "PATCH", "/tasks/"+id+"/subtasks/"+subtaskID, `{"checked": true}`,
+			"id", id, "subtaskId", subtaskID)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("PATCH of subtask %s: got %d, want 404", subtaskID, w.Code)
+		}
+	}
+	w = serve(createSubtask, "POST", "/tasks/tk-unknown/subtasks", `{"title": "Orphan"}`,
+		"id", "tk-unknown")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("POST to an unknown task: got %d, want 404", w.Code)
+	}
+
+	w = serve(deleteSubtask, "DELETE", "/tasks/"+id+"/subtasks/"+first, "", "id", id, "subtaskId", first)
+	if w.Code != http.StatusNoContent || w.Header().Get("ETag") != etag(6) {
+		t.Errorf("DELETE: got %d with ETag %s, want 204 with \"6\"", w.Code, w.Header().Get("ETag"))
+	}
+	got := readTask(t, b, id)
+	if len(got.Subtasks) != 2 || got.Subtasks[0].SubtaskId != subtaskIDs[1] {
+		t.Errorf("DELETE: got subtasks %+v", got.Subtasks)
+	}
+}
+
+// The `TestReorderSubtasks` test checks that the new order has to list every subtask of the task
+// exactly once.
+func TestReorderSubtasks(t *testing.T) {
+	b := newTestBoard(t)
+	task := &structures.Task{Title: "Parent", Status: structures.StatusToDo,
+		Prio: structures.PriorityLow, Category: testCategory,
+		Subtasks: []structures.Subtask{{Title: "One"}, {Title: "Two"}, {Title: "Three"}}}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+	id := task.ID_task
+	one, two, three := task.Subtasks[0].SubtaskId, task.Subtasks[1].SubtaskId, task.Subtasks[2].SubtaskId
+
+	tests := []struct {
+		name  string
+		order []int64
+		want  int
+	}{
+		{"missing subtask", []int64{three, one}, http.StatusUnprocessableEntity},
+		{"repeated subtask", []int64{three, one, one}, http.StatusUnprocessableEntity},
+		{"unknown subtask", []int64{three, one, 4}, http.StatusUnprocessableEntity},
+		{"extra subtask", []int64{three, one, two, 4}, http.StatusUnprocessableEntity},
+		{"no list", nil, http.StatusUnprocessableEntity},
+		{"complete order", []int64{three, one, two}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		order, _ := json.Marshal(tt.order)
+		body := fmt.Sprintf(`{"order": %s}`, order)
+		w := serve(reorderSubtasks, "PUT", "/tasks/"+id+"/subtasks/order", body, "id", id)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+
+	got := readTask(t, b, id)
+	var order []int64
+	for _, sub := range got.Subtasks {
+		order = append(order, sub.SubtaskId)
+	}
+	if want := []int64{three, one, two}; fmt.Sprint(order) != fmt.Sprint(want) || got.Version != 2 {
+		t.Errorf("got order %v and version %d, want %v and 2", order, got.Version, want)
+	}
+}
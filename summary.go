@@ -0,0 +1,119 @@
+package main
+
+import (
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// The `summary` struct is the response of `GET /summary`. It contains the figures that the summary
+// page and the board headers show, so the frontend does not have to download every task to compute
+// them.
+type summary struct {
+	Total    int                         `json:"total"`
+	ByStatus map[structures.Status]int   `json:"by_status"`
+	ByPrio   map[structures.Priority]int `json:"by_prio"`
+	Overdue  int                         `json:"overdue"`
+	Urgent   urgentSummary               `json:"urgent"`
+	Subtasks map[string]subtaskProgress  `json:"subtasks"`
+}
+
+// The `urgentSummary` struct counts the open urgent tasks and lists those with the nearest due date.
+type urgentSummary struct {
+	Open        int                `json:"open"`
+	NextDueDate string             `json:"next_due_date,omitempty"`
+	Next        []*structures.Task `json:"next"`
+}
+
+// The `subtaskProgress` struct is the subtask completion of one task, e.g. "3/5 subtasks done".
+type subtaskProgress struct {
+	Done  int     `json:"done"`
+	Total int     `json:"total"`
+	Ratio float64 `json:"ratio"`
+}
+
+// The `summaryHandler` function handles `GET /summary`. It counts the tasks per status and priority,
+// the open tasks whose due date has passed, the open urgent tasks together with the ones due next, and
+// the subtask completion of every task. Tasks with the status "Done" are never overdue or open.
+func summaryHandler(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var allTasks map[string]*structures.Task
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		allTasks, err = tx.Tasks()
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summarize(allTasks, time.Now().Format(validation.DateLayout)))
+}
+
+// The `summarize` function computes the summary of `allTasks` on the date `today`, given in the
+// format of `Task.Due_date`.
+func summarize(allTasks map[string]*structures.Task, today string) summary {
+	s := summary{
+		Total:    len(allTasks),
+		ByStatus: make(map[structures.Status]int),
+		ByPrio:   make(map[structures.Priority]int),
+		Urgent:   urgentSummary{Next: []*structures.Task{}},
+		Subtasks: make(map[string]subtaskProgress, len(allTasks)),
+	}
+
+	// Every known status and priority is listed, even with a count of zero, so that the frontend can
+	// render empty columns without knowing the enums.
+	for _, status := range structures.Statuses {
+		s.ByStatus[status] = 0
+	}
+	for _, prio := range structures.Priorities {
+		s.ByPrio[prio] = 0
+	}
+
+	for id, t := range allTasks {
+		s.ByStatus[t.Status]++
+		s.ByPrio[t.Prio]++
+
+		progress := subtaskProgress{Total: len(t.Subtasks)}
+		for _, sub := range t.Subtasks {
+			if sub.Checked {
+				progress.Done++
+			}
+		}
+		if progress.Total > 0 {
+			progress.Ratio = float64(progress.Done) / float64(progress.Total)
+		}
+		s.Subtasks[id] = progress
+
+		if t.Status == structures.StatusDone {
+			continue
+		}
+		if t.Due_date != "" && t.Due_date < today {
+			s.Overdue++
+		}
+		if t.Prio != structures.PriorityUrgent {
+			continue
+		}
+
+		// The urgent tasks due next are collected while counting: an earlier due date replaces the
+		// list, the same date extends it. Urgent tasks without a due date are counted but never listed.
+		s.Urgent.Open++
+		switch {
+		case t.Due_date == "":
+		case s.Urgent.NextDueDate == "" || t.Due_date < s.Urgent.NextDueDate:
+			s.Urgent.NextDueDate = t.Due_date
+			s.Urgent.Next = []*structures.Task{t}
+		case t.Due_date == s.Urgent.NextDueDate:
+			s.Urgent.Next = append(s.Urgent.Next, t)
+		}
+	}
+
+	sort.Slice(s.Urgent.Next, func(i, j int) bool {
+		return s.Urgent.Next[i].ID_task < s.Urgent.Next[j].ID_task
+	})
+	return s
+}
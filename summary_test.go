@@ -0,0 +1,124 @@
+package main
+
+import (
+	"minibackend/structures"
+	"reflect"
+	"testing"
+)
+
+// The `TestSummarize` test computes the summary of small sets of tasks on a fixed date.
+func TestSummarize(t *testing.T) {
+	const today = "2024-03-15"
+	task := func(id string, status structures.Status, prio structures.Priority, due string,
+		checked ...bool) *structures.Task {
+		tk := &structures.Task{ID_task: id, Status: status, Prio: prio, Due_date: due}
+		for _, c := range checked {
+			tk.Subtasks = append(tk.Subtasks, structures.Subtask{Checked: c})
+		}
+		return tk
+	}
+	const (
+		todo, progress, done = structures.StatusToDo, structures.StatusProgress, structures.StatusDone
+		low, urgent          = structures.PriorityLow, structures.PriorityUrgent
+	)
+
+	tests := []struct {
+		name     string
+		tasks    []*structures.Task
+		overdue  int
+		urgent   int
+		nextDue  string
+		next     []string
+		progress map[string]subtaskProgress
+	}{
+		{
+			name:     "no tasks",
+			next:     []string{},
+			progress: map[string]subtaskProgress{},
+		},
+		{
+			name: "due today is not overdue",
+			tasks: []*structures.Task{
+				task("a", todo, low, "2024-03-14"),
+				task("b", todo, low, today),
+				task("c", todo, low, ""),
+			},
+			overdue:  1,
+			next:     []string{},
+			progress: map[string]subtaskProgress{"a": {}, "b": {}, "c": {}},
+		},
+		{
+			name: "done tasks are neither overdue nor open",
+			tasks: []*structures.Task{
+				task("a", done, urgent, "2024-01-01"),
+				task("b", progress, urgent, "2024-01-02"),
+			},
+			overdue:  1,
+			urgent:   1,
+			nextDue:  "2024-01-02",
+			next:     []string{"b"},
+			progress: map[string]subtaskProgress{"a": {}, "b": {}},
+		},
+		{
+			name: "urgent tasks due next",
+			tasks: []*structures.Task{
+				task("d", todo, urgent, "2024-04-01"),
+				task("c", todo, urgent, "2024-03-20"),
+				task("b", todo, urgent, ""),
+				task("a", progress, urgent, "2024-03-20"),
+			},
+			urgent:   4,
+			nextDue:  "2024-03-20",
+			next:     []string{"a", "c"},
+			progress: map[string]subtaskProgress{"a": {}, "b": {}, "c": {}, "d": {}},
+		},
+		{
+			name: "subtask progress",
+			tasks: []*structures.Task{
+				task("a", todo, low, "", true, false, false, true),
+				task("b", todo, low, "", true),
+				task("c", todo, low, "", false),
+			},
+			next: []string{},
+			progress: map[string]subtaskProgress{
+				"a": {Done: 2, Total: 4, Ratio: 0.5},
+				"b": {Done: 1, Total: 1, Ratio: 1},
+				"c": {Done: 0, Total: 1, Ratio: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		// Every status and priority is counted, with zero for those no task has.
+		all := map[string]*structures.Task{}
+		byStatus := map[structures.Status]int{todo: 0, progress: 0, structures.StatusFeedback: 0,
+			done: 0}
+		byPrio := map[structures.Priority]int{low: 0, structures.PriorityMedium: 0, urgent: 0}
+		for _, tk := range tt.tasks {
+			all[tk.ID_task] = tk
+			byStatus[tk.Status]++
+			byPrio[tk.Prio]++
+		}
+
+		s := summarize(all, today)
+		next := []string{}
+		for _, tk := range s.Urgent.Next {
+			next = append(next, tk.ID_task)
+		}
+		if s.Total != len(tt.tasks) || !reflect.DeepEqual(s.ByStatus, byStatus) ||
+			!reflect.DeepEqual(s.ByPrio, byPrio) {
+			t.Errorf("%s: got total %d, by status %v, by prio %v",
+				tt.name, s.Total, s.ByStatus, s.ByPrio)
+		}
+		if s.Overdue != tt.overdue {
+			t.Errorf("%s: got %d overdue tasks, want %d", tt.name, s.Overdue, tt.overdue)
+		}
+		if s.Urgent.Open != tt.urgent || s.Urgent.NextDueDate != tt.nextDue ||
+			!reflect.DeepEqual(next, tt.next) {
+			t.Errorf("%s: got %d open urgent tasks, next due %q: %v; want %d, %q: %v", tt.name,
+				s.Urgent.Open, s.Urgent.NextDueDate, next, tt.urgent, tt.nextDue, tt.next)
+		}
+		if !reflect.DeepEqual(s.Subtasks, tt.progress) {
+			t.Errorf("%s: got subtask progress %v, want %v", tt.name, s.Subtasks, tt.progress)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The `DefaultPageSize` and `MaxPageSize` constants bound the `limit` parameter of `GET /tasks`.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// The `taskSortKeys` variable maps the fields accepted by `?sort=` to the function that returns the
+// sort key of a task for that field. Keys are compared as strings, so enums are sorted by their rank
+// and tasks without a due date come after all dated tasks.
+var taskSortKeys = map[string]func(t *structures.Task) string{
+	"id":       func(t *structures.Task) string { return "" },
+	"title":    func(t *structures.Task) string { return strings.ToLower(t.Title) },
+	"due_date": func(t *structures.Task) string { return orDefault(t.Due_date, "9999-99-99") },
+	"status":   func(t *structures.Task) string { return rank(t.Status, structures.Statuses) },
+	"prio":     func(t *structures.Task) string { return rank(t.Prio, structures.Priorities) },
+	"category": func(t *structures.Task) string { return t.Category },
+}
+
+// The `taskQuery` struct is the parsed query string of `GET /tasks`. Every filter holds the accepted
+// values of one parameter; an empty filter accepts every task. `paged` is set when the client asked
+// for sorting or pagination, which switches the response from the ID-keyed object to a page.
+type taskQuery struct {
+	statuses   []structures.Status
+	prios      []structures.Priority
+	assignees  []string
+	categories []string
+	dueBefore  string
+	dueAfter   string
+
+	sortParam string
+	sort      string
+	desc      bool
+	sortBy    func(t *structures.Task) string
+	limit     int
+	after     *taskCursor
+	paged     bool
+}
+
+// The `taskCursor` struct is the position after which the next page starts: the sort key and ID of
+// the last task of the previous page. It is sent to the client as an opaque base64 string.
+type taskCursor struct {
+	Sort string `json:"sort"`
+	Key  string `json:"key"`
+	ID   string `json:"id"`
+}
+
+// The `taskPage` struct is the response of a sorted or paginated `GET /tasks`. `NextCursor` is empty
+// on the last page.
+type taskPage struct {
+	Items      []*structures.Task `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// The `parseTaskQuery` function parses the filters, sort order and pagination parameters of
+// `GET /tasks`. Filters accept several values, either repeated or separated by commas. Invalid values
+// result in a 400 error naming the parameter.
+func parseTaskQuery(values url.Values) (*taskQuery, error) {
+	q := &taskQuery{sort: "id", limit: DefaultPageSize}
+
+	for _, s := range queryList(values, "status") {
+		status, err := structures.ParseStatus(s)
+		if err != nil {
+			return nil, errInvalidQuery("status", err.Error())
+		}
+		q.statuses = append(q.statuses, status)
+	}
+	for _, s := range queryList(values, "prio") {
+		prio, err := structures.ParsePriority(s)
+		if err != nil {
+			return nil, errInvalidQuery("prio", err.Error())
+		}
+		q.prios = append(q.prios, prio)
+	}
+
+	// `assigned` is the name of the old single-assignee field, `assignee` the one introduced with
+	// multiple assignees; both filter on the same list.
+	q.assignees = append(queryList(values, "assigned"), queryList(values, "assignee")...)
+	q.categories = queryList(values, "category")
+
+	for name, date := range map[string]*string{"due_before": &q.dueBefore, "due_after": &q.dueAfter} {
+		*date = values.Get(name)
+		if _, err := time.Parse(validation.DateLayout, *date); *date != "" && err != nil {
+			return nil, errInvalidQuery(name, "must be a date in the format YYYY-MM-DD")
+		}
+	}
+
+	if q.sortParam = values.Get("sort"); q.sortParam != "" {
+		q.paged = true
+		q.sort = strings.TrimPrefix(q.sortParam, "-")
+		q.desc = strings.HasPrefix(q.sortParam, "-")
+	}
+	var ok bool
+	if q.sortBy, ok = taskSortKeys[q.sort]; !ok {
+		return nil, errInvalidQuery("sort", fmt.Sprintf("unknown sort field %q", q.sort))
+	}
+
+	if s := values.Get("limit"); s != "" {
+		q.paged = true
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return nil, errInvalidQuery("limit", fmt.Sprintf("must be a number between 1 and %d", MaxPageSize))
+		}
+		q.limit = limit
+	}
+
+	// The cursor remembers the sort order it was issued for, because its key means nothing under a
+	// different order.
+	if s := values.Get("cursor"); s != "" {
+		q.paged = true
+		data, err := base64.RawURLEncoding.DecodeString(s)
+		if err == nil {
+			err = json.Unmarshal(data, &q.after)
+		}
+		if err != nil || q.after == nil || q.after.Sort != q.sortParam {
+			return nil, errInvalidQuery("cursor", "invalid cursor or cursor issued for a different sort order")
+		}
+	}
+	return q, nil
+}
+
+// The `match` method reports whether `t` passes every filter of the query.
+func (q *taskQuery) match(t *structures.Task) bool {
+	if len(q.statuses) > 0 && !contains(q.statuses, t.Status) {
+		return false
+	}
+	if len(q.prios) > 0 && !contains(q.prios, t.Prio) {
+		return false
+	}
+	if len(q.assignees) > 0 && !hasAnyAssignee(t, q.assignees) {
+		return false
+	}
+	if len(q.categories) > 0 && !contains(q.categories, t.Category) {
+		return false
+	}
+	if q.dueBefore != "" && (t.Due_date == "" || t.Due_date >= q.dueBefore) {
+		return false
+	}
+	if q.dueAfter != "" && (t.Due_date == "" || t.Due_date <= q.dueAfter) {
+		return false
+	}
+	return true
+}
+
+// The `less` method reports whether the task with sort key `keyA` and ID `idA` comes before the one
+// with `keyB` and `idB` in the sort order of the query. The task ID
+// breaks ties, so the order is total and stays the same between requests, which the cursor relies on.
+func (q *taskQuery) less(keyA, idA, keyB, idB string) bool {
+	if keyA == keyB {
+		keyA, keyB = idA, idB
+	}
+	if q.desc {
+		return keyA > keyB
+	}
+	return keyA < keyB
+}
+
+// The `page` method filters and sorts `all` and returns the page selected by the cursor and limit of
+// the query, together with the number of tasks that match the filters and the cursor of the next
+// page.
+func (q *taskQuery) page(all map[string]*structures.Task) (page taskPage, total int) {
+	matched := make([]*structures.Task, 0, len(all))
+	for _, t := range all {
+		if q.match(t) {
+			matched = append(matched, t)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return q.less(q.sortBy(matched[i]), matched[i].ID_task, q.sortBy(matched[j]), matched[j].ID_task)
+	})
+
+	start := 0
+	if q.after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return q.less(q.after.Key, q.after.ID, q.sortBy(matched[i]), matched[i].ID_task)
+		})
+	}
+	end := min(start+q.limit, len(matched))
+
+	page.Items = matched[start:end]
+	if end < len(matched) {
+		last := matched[end-1]
+		data, _ := json.Marshal(taskCursor{Sort: q.sortParam, Key: q.sortBy(last), ID: last.ID_task})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return page, len(matched)
+}
+
+// The `errInvalidQuery` function returns the 400 error for an invalid query parameter.
+func errInvalidQuery(param, message string) error {
+	return &httpError{http.StatusBadRequest, "invalid_query", param + ": " + message}
+}
+
+// The `queryList` function returns the values of the query parameter `name`, accepting both repeated
+// parameters and comma-separated lists. Empty entries are dropped.
+func queryList(values url.Values, name string) []string {
+	var list []string
+	for _, v := range values[name] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// The `contains` function reports whether `v` is one of `list`.
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// The `rank` function returns the position of `v` in `order` as a zero-padded string, so that enum
+// values sort by their meaning rather than alphabetically. Unknown values sort last.
+func rank[T comparable](v T, order []T) string {
+	for i, item := range order {
+		if item == v {
+			return fmt.Sprintf("%03d", i)
+		}
+	}
+	return "999"
+}
+
+// The `orDefault` function returns `s`, or `def` if `s` is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
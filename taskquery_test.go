@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// The `listPage` function calls `GET /tasks` with the query `query` and returns the decoded page and
+// the X-Total-Count header.
+func listPage(t *testing.T, query string) (taskPage, string) {
+	t.Helper()
+	w := serve(tasks, "GET", "/tasks?"+query, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /tasks?%s: got %d %s", query, w.Code, w.Body)
+	}
+	var page taskPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page, w.Header().Get("X-Total-Count")
+}
+
+// The `pageIDs` function returns the IDs of the tasks of `page` in order.
+func pageIDs(page taskPage) []string {
+	var ids []string
+	for _, task := range page.Items {
+		ids = append(ids, task.ID_task)
+	}
+	return ids
+}
+
+// The `TestTaskPagination` test walks through the tasks sorted by a field on which they all share the
+// same value, so the order comes from the ID tie-break alone. Tasks inserted between two pages must
+// not shift the later pages, and every task must be seen exactly once.
+func TestTaskPagination(t *testing.T) {
+	b := newTestBoard(t)
+	for i := 0; i < 7; i++ {
+		task := &structures.Task{ID_task: fmt.Sprintf("tk-%02d", i), Title: "Same",
+			Status: structures.StatusToDo, Prio: structures.PriorityLow, Category: testCategory}
+		if err := b.update(func(tx storage.Tx) error { return tx.PutTask(task) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, total := listPage(t, "sort=title&limit=3")
+	if total != "7" {
+		t.Errorf("got X-Total-Count %s, want 7", total)
+	}
+	seen := pageIDs(page)
+
+	// Two tasks are inserted after the first page. The one that sorts before the cursor belongs to a
+	// page that has already been read and must not push another task off the next one; the one after
+	// it has to show up.
+	for _, id := range []string{"tk-00a", "tk-03a"} {
+		task := &structures.Task{ID_task: id, Title: "Same", Status: structures.StatusToDo,
+			Prio: structures.PriorityLow, Category: testCategory}
+		if err := b.update(func(tx storage.Tx) error { return tx.PutTask(task) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for page.NextCursor != "" {
+		page, _ = listPage(t, "sort=title&limit=3&cursor="+url.QueryEscape(page.NextCursor))
+		seen = append(seen, pageIDs(page)...)
+	}
+	want := []string{"tk-00", "tk-01", "tk-02", "tk-03", "tk-03a", "tk-04", "tk-05", "tk-06"}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("got tasks %v, want %v", seen, want)
+	}
+
+	// The same walk in descending order uses the tie-break the other way round.
+	page, _ = listPage(t, "sort=-title&limit=4")
+	if got := pageIDs(page); fmt.Sprint(got) != "[tk-06 tk-05 tk-04 tk-03a]" {
+		t.Errorf("descending: got first page %v, want [tk-06 tk-05 tk-04 tk-03a]", got)
+	}
+}
+
+// The `TestTaskFilters` test checks that the filters select the right tasks and that X-Total-Count
+// counts every match, not only those on the page.
+func TestTaskFilters(t *testing.T) {
+	b := newTestBoard(t)
+	for i, status := range []structures.Status{structures.StatusToDo, structures.StatusToDo,
+		structures.StatusToDo, structures.StatusDone} {
+		task := &structures.Task{Title: fmt.Sprintf("Task %d", i), Status: status,
+			Prio: structures.PriorityLow, Category: testCategory, Due_date: fmt.Sprintf("2024-03-0%d", i+1)}
+		if err := insertTask(b, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		items int
+		total string
+	}{
+		{"status=ToDo&limit=2", 2, "3"},
+		{"status=ToDo,Done&limit=1", 1, "4"},
+		{"status=Done&sort=title", 1, "1"},
+		{"due_before=2024-03-03&sort=due_date", 2, "2"},
+		{"due_after=2024-03-01&status=ToDo&sort=due_date", 2, "2"},
+		{"category=cat-other&sort=title", 0, "0"},
+	}
+	for _, tt := range tests {
+		page, total := listPage(t, tt.query)
+		if len(page.Items) != tt.items || total != tt.total {
+			t.Errorf("%s: got %d tasks and X-Total-Count %s, want %d and %s",
+				tt.query, len(page.Items), total, tt.items, tt.total)
+		}
+	}
+}
+
+// The `TestInvalidTaskQuery` test checks that malformed parameters are rejected with a 400 error
+// naming the parameter instead of being ignored.
+func TestInvalidTaskQuery(t *testing.T) {
+	b := newTestBoard(t)
+	for _, title := range []string{"First", "Second"} {
+		if err := insertTask(b, &structures.Task{Title: title, Status: structures.StatusToDo,
+			Prio: structures.PriorityLow, Category: testCategory}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, _ := listPage(t, "sort=title&limit=1")
+	otherOrder := url.QueryEscape(page.NextCursor)
+
+	for _, query := range []string{
+		"limit=0",
+		"limit=abc",
+		fmt.Sprintf("limit=%d", MaxPageSize+1),
+		"cursor=not-base64!",
+		"cursor=bm90IGpzb24",
+		"sort=due_date&cursor=" + otherOrder,
+		"sort=owner",
+		"status=Someday",
+		"due_before=01.04.2024",
+	} {
+		w := serve(tasks, "GET", "/tasks?"+query, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d %s, want 400", query, w.Code, w.Body)
+		}
+	}
+}
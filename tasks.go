@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"minibackend/ids"
+	"minibackend/jsonpatch"
+	"minibackend/storage"
+	"minibackend/structures"
+	"minibackend/validation"
+	"net/http"
+	"strconv"
+)
+
+// The function `tasks` loads all tasks from the store and serves them as a JSON object keyed by task
+// ID. It handles `GET /tasks`. The query string can filter the tasks by `status`, `prio`, `assigned`
+// (or `assignee`), `category`, `due_before` and `due_after`. Asking for a `sort` order, a `limit` or a
+// `cursor` switches the response to a page of the form {"items": [...], "next_cursor": "..."}, so that
+// the board can load its columns lazily. The `X-Total-Count` header always holds the number of tasks
+// matching the filters.
+func tasks(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	query, err := parseTaskQuery(r.URL.Query())
+	if err != nil {
+		writeStoreError(w, err, err.Error())
+		return
+	}
+
+	// The tasks are read in a read-only transaction. If the store cannot be read, a 500 Internal Server
+	// Error response with the error message is returned.
+	var allTasks map[string]*structures.Task
+	err = b.store.View(func(tx storage.Tx) error {
+		var err error
+		allTasks, err = tx.Tasks()
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if query.paged {
+		page, total := query.page(allTasks)
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+
+	for id, t := range allTasks {
+		if !query.match(t) {
+			delete(allTasks, id)
+		}
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(allTasks)))
+	writeJSON(w, http.StatusOK, allTasks)
+}
+
+// The `getTask` function handles `GET /tasks/{id}` and serves the single task with that ID, so that
+// the frontend does not have to download every task to open one detail dialog. An unknown ID results in
+// a structured 404 Not Found error.
+func getTask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	id := r.PathValue("id")
+
+	var task *structures.Task
+	err := b.store.View(func(tx storage.Tx) error {
+		var err error
+		task, err = tx.Task(id)
+		return err
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		err = errTaskNotFound(id)
+	}
+	if err != nil {
+		writeStoreError(w, err, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag(task.Version))
+	writeJSON(w, http.StatusOK, task)
+}
+
+// The `createTask` function handles `POST /tasks`. It stores the task from the request body under a
+// new server-generated ID and answers with 201 Created, the new task and its location.
+func createTask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+
+	// The request body is decoded into a new task. Malformed JSON, as well as an unknown status or
+	// priority, results in a structured 400 Bad Request response with the decoding error.
+	newTask := &structures.Task{}
+	if err := json.NewDecoder(r.Body).Decode(newTask); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+
+	if err := insertTask(b, newTask); err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("Location", b.path("/tasks/"+newTask.ID_task))
+	w.Header().Set("ETag", etag(newTask.Version))
+	writeJSON(w, http.StatusCreated, newTask)
+}
+
+// The `replaceTask` function handles `PUT /tasks/{id}`. The task from the request body replaces the
+// stored task with the ID from the path; an ID in the body is ignored. An unknown ID results in 404 Not
+// Found, unless the request explicitly asks for an upsert with `?upsert=true`, in which case the task
+// is created under that ID and the response is 201 Created. An `If-Match` header makes the replacement
+// conditional on the current ETag of the task.
+func replaceTask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	var task structures.Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	task.ID_task = r.PathValue("id")
+
+	created, err := saveTask(b, &task, r.URL.Query().Get("upsert") == "true", r.Header.Get("If-Match"))
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("ETag", etag(task.Version))
+	if created {
+		w.Header().Set("Location", b.path("/tasks/"+task.ID_task))
+		writeJSON(w, http.StatusCreated, &task)
+		return
+	}
+	writeJSON(w, http.StatusOK, &task)
+}
+
+// The `patchTask` function handles `PATCH /tasks/{id}`. Only the fields contained in the patch are
+// changed, so dragging a card to another column only needs to send the new status, and concurrent
+// edits of other fields are not overwritten. The patch format is selected by the Content-Type header:
+// "application/json-patch+json" for a JSON Patch (RFC 6902), and "application/merge-patch+json" or
+// plain "application/json" for a JSON Merge Patch (RFC 7396). An `If-Match` header makes the patch
+// conditional on the current ETag of the task.
+func patchTask(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	id := r.PathValue("id")
+
+	// The patch function is chosen before the body is read, so that an unsupported format is rejected
+	// with 415 Unsupported Media Type without touching the store.
+	apply := patchFormat(w, r)
+	if apply == nil {
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusInternalServerError)
+		return
+	}
+
+	// The stored task is patched as a JSON document and decoded again inside one transaction, so no
+	// other update can slip in between reading and writing the task. The ID is taken from the path, so
+	// a patch cannot move the task to another ID.
+	var task *structures.Task
+	err = b.update(func(tx storage.Tx) error {
+		current, err := tx.Task(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errTaskNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(r.Header.Get("If-Match"), true, current.Version); err != nil {
+			return err
+		}
+
+		doc, err := taskDocument(current)
+		if err != nil {
+			return err
+		}
+		task = &structures.Task{}
+		if err := applyPatch(apply, doc, patch, task); err != nil {
+			return err
+		}
+		task.ID_task = id
+		task.Version = current.Version + 1
+		task.FillSubtaskIDs(ids.NewSubtaskID)
+
+		if err := validateTask(tx, task); err != nil {
+			return err
+		}
+		return tx.PutTask(task)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+
+	w.Header().Set("ETag", etag(task.Version))
+	writeJSON(w, http.StatusOK, task)
+}
+
+// The `patchFormat` function returns the function that applies the patch in the body of `r`, chosen by
+// its Content-Type header as described for `patchTask`. An unsupported format results in a 415
+// Unsupported Media Type response and a nil function.
+func patchFormat(w http.ResponseWriter, r *http.Request) func(doc, patch []byte) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case jsonpatch.MergePatchType, "application/json", "":
+		return jsonpatch.MergePatch
+	case jsonpatch.JSONPatchType:
+		return jsonpatch.Apply
+	}
+	writeError(w, http.StatusUnsupportedMediaType, "unsupported_media_type",
+		fmt.Sprintf("use %s or %s", jsonpatch.MergePatchType, jsonpatch.JSONPatchType))
+	return nil
+}
+
+// The `taskDocument` function returns the JSON document a patch of `t` is applied to. It leaves out
+// the deprecated `assigned` field, which only mirrors the first assignee: left in, its old value would
+// be added back to the assignees after a patch that removed it.
+func taskDocument(t *structures.Task) (json.RawMessage, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "assigned")
+	return json.Marshal(fields)
+}
+
+// The `applyPatch` function applies `patch` with `apply` to the JSON form of `current` and decodes the
+// result into `patched`. A failed JSON Patch test results in a 409 error and any other problem with
+// the patch in a 400 error.
+func applyPatch(apply func(doc, patch []byte) ([]byte, error), current any, patch []byte, patched any) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	result, err := apply(doc, patch)
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return &httpError{http.StatusConflict, "patch_test_failed", err.Error()}
+	}
+	if err != nil {
+		return &httpError{http.StatusBadRequest, "invalid_patch", err.Error()}
+	}
+	if err := json.Unmarshal(result, patched); err != nil {
+		return &httpError{http.StatusBadRequest, "invalid_patch", err.Error()}
+	}
+	return nil
+}
+
+// The `deleteTaskByID` function handles `DELETE /tasks/{id}` and answers with 204 No Content. An
+// `If-Match` header makes the deletion conditional on the current ETag of the task.
+func deleteTaskByID(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	if err := removeTask(b, r.PathValue("id"), r.Header.Get("If-Match")); err != nil {
+		writeStoreError(w, err, "Error writing updated tasks")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// The `insertTask` function validates `t` and stores it as a new task. The task and all of its
+// subtasks get server-generated IDs, since nothing can reference them yet, and the task starts at
+// version 1.
+func insertTask(b *board, t *structures.Task) error {
+	t.Version = 1
+	for i := range t.Subtasks {
+		t.Subtasks[i].SubtaskId = ids.NewSubtaskID()
+	}
+
+	return b.update(func(tx storage.Tx) error {
+		if err := validateTask(tx, t); err != nil {
+			return err
+		}
+		var err error
+		t.ID_task, err = newTaskID(tx)
+		if err != nil {
+			return err
+		}
+		return tx.PutTask(t)
+	})
+}
+
+// The `saveTask` function validates `t` and stores it under its `ID_task`, replacing the existing
+// task with that ID. If there is no such task, it fails with a 404 error unless `upsert` is set, in
+// which case the task is created and `created` is true. An empty ID is always rejected, and a non-empty
+// `ifMatch` has to match the ETag of the stored task. The version of the task is set by the server,
+// whatever the client sent. Subtasks added on the client arrive without an ID (or with one that
+// clashes with another subtask of the task) and get a server-generated one.
+func saveTask(b *board, t *structures.Task, upsert bool, ifMatch string) (created bool, err error) {
+	if t.ID_task == "" {
+		return false, &httpError{http.StatusBadRequest, "missing_id", "Task ID not provided"}
+	}
+	t.FillSubtaskIDs(ids.NewSubtaskID)
+
+	err = b.update(func(tx storage.Tx) error {
+		current, err := tx.Task(t.ID_task)
+		switch {
+		case errors.Is(err, storage.ErrNotFound) && upsert:
+			created = true
+		case errors.Is(err, storage.ErrNotFound):
+			return errTaskNotFound(t.ID_task)
+		case err != nil:
+			return err
+		}
+
+		if created {
+			err = checkIfMatch(ifMatch, false, 0)
+			t.Version = 1
+		} else {
+			err = checkIfMatch(ifMatch, true, current.Version)
+			t.Version = current.Version + 1
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := validateTask(tx, t); err != nil {
+			return err
+		}
+		return tx.PutTask(t)
+	})
+	return created, err
+}
+
+// The `removeTask` function deletes the task with the given ID. Deleting an ID that does not exist
+// fails with a 404 error, and a non-empty `ifMatch` has to match the ETag of the task.
+func removeTask(b *board, id, ifMatch string) error {
+	return b.update(func(tx storage.Tx) error {
+		current, err := tx.Task(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return errTaskNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkIfMatch(ifMatch, true, current.Version); err != nil {
+			return err
+		}
+		return tx.DeleteTask(id)
+	})
+}
+
+// The `errTaskNotFound` function returns the 404 error for the unknown task `id`.
+func errTaskNotFound(id string) error {
+	return &httpError{http.StatusNotFound, "not_found", fmt.Sprintf("task %q not found", id)}
+}
+
+// The `newTaskID` function returns a new task ID that is not used by any task in `tx`.
+func newTaskID(tx storage.Tx) (string, error) {
+	return ids.Unique("tk", func(id string) (bool, error) {
+		_, err := tx.Task(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+}
+
+// The `hasAnyAssignee` function reports whether any of the contacts `contactIDs` is assigned to `t`.
+func hasAnyAssignee(t *structures.Task, contactIDs []string) bool {
+	for _, id := range contactIDs {
+		if t.HasAssignee(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// The `validateTask` function checks `t` against the task rules of the `validation` package. The
+// category and contact it references are looked up in `tx`, so the check sees the same data the task
+// is written to.
+func validateTask(tx storage.Tx, t *structures.Task) error {
+	categories, err := tx.Categories()
+	if err != nil {
+		return err
+	}
+	contacts, err := tx.Contacts()
+	if err != nil {
+		return err
+	}
+	if errs := validation.Task(t, categories, contacts); errs != nil {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"minibackend/structures"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The `TestReplaceTaskIDs` test covers how `PUT /tasks/{id}` and `DELETE /tasks/{id}` treat IDs: an
+// empty ID is rejected, an unknown one is not found unless an upsert is asked for, and an upsert
+// creates the task under the ID from the path.
+func TestReplaceTaskIDs(t *testing.T) {
+	newTestBoard(t)
+	body := taskJSON("Upserted")
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		id      string
+		want    int
+	}{
+		{"PUT without ID", replaceTask, "PUT", "/tasks/", "", http.StatusBadRequest},
+		{"PUT unknown ID", replaceTask, "PUT", "/tasks/tk-new", "tk-new", http.StatusNotFound},
+		{"DELETE unknown ID", deleteTaskByID, "DELETE", "/tasks/tk-new", "tk-new", http.StatusNotFound},
+		{"PUT upsert", replaceTask, "PUT", "/tasks/tk-new?upsert=true", "tk-new", http.StatusCreated},
+		{"PUT existing ID", replaceTask, "PUT", "/tasks/tk-new", "tk-new", http.StatusOK},
+		{"PUT upsert of existing ID", replaceTask, "PUT", "/tasks/tk-new?upsert=true", "tk-new", http.StatusOK},
+		{"DELETE existing ID", deleteTaskByID, "DELETE", "/tasks/tk-new", "tk-new", http.StatusNoContent},
+		{"DELETE deleted ID", deleteTaskByID, "DELETE", "/tasks/tk-new", "tk-new", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := serve(tt.handler, tt.method, tt.target, body, "id", tt.id)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+		if tt.want == http.StatusCreated && w.Header().Get("Location") != "/tasks/tk-new" {
+			t.Errorf("%s: got Location %q", tt.name, w.Header().Get("Location"))
+		}
+	}
+}
+
+// The `TestTaskAssignedField` test checks that tasks are still served with the deprecated `assigned`
+// field, and that a patch of the assignees is not undone by it.
+func TestTaskAssignedField(t *testing.T) {
+	b := newTestBoard(t)
+	var ids []string
+	for _, name := range []string{"Ada", "Grace"} {
+		c := &structures.Contact{First_Name: name, Email: strings.ToLower(name) + "@example.com"}
+		if err := insertContact(b, c); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, c.ID_contact)
+	}
+	task := &structures.Task{Title: "Pair", Status: structures.StatusToDo, Prio: structures.PriorityLow, Category: testCategory, Assignees: ids}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(getTask, "GET", "/tasks/"+task.ID_task, "", "id", task.ID_task)
+	if !strings.Contains(w.Body.String(), fmt.Sprintf(`"assigned":%q`, ids[0])) {
+		t.Errorf("GET: %s has no assigned %s", w.Body, ids[0])
+	}
+
+	patch := fmt.Sprintf(`{"assignees": [%q]}`, ids[1])
+	w = serve(patchTask, "PATCH", "/tasks/"+task.ID_task, patch, "id", task.ID_task)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: got %d %s", w.Code, w.Body)
+	}
+	var patched structures.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &patched); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(patched.Assignees, []string{ids[1]}) {
+		t.Errorf("PATCH: got assignees %v, want [%s]", patched.Assignees, ids[1])
+	}
+	if !strings.Contains(w.Body.String(), fmt.Sprintf(`"assigned":%q`, ids[1])) {
+		t.Errorf("PATCH: %s has no assigned %s", w.Body, ids[1])
+	}
+}
+
+// The `TestPatchTask` test covers the error answers of `PATCH /tasks/{id}` and checks that the
+// deprecated `assigned` field is not part of the document a patch is applied to.
+func TestPatchTask(t *testing.T) {
+	b := newTestBoard(t)
+	c := &structures.Contact{First_Name: "Ada", Email: "ada@example.com"}
+	if err := insertContact(b, c); err != nil {
+		t.Fatal(err)
+	}
+	task := &structures.Task{Title: "Patch me", Status: structures.StatusToDo, Prio: structures.PriorityLow, Category: testCategory, Assignees: []string{c.ID_contact}}
+	if err := insertTask(b, task); err != nil {
+		t.Fatal(err)
+	}
+	id := task.ID_task
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        int
+		wantCode    string
+	}{
+		{"unknown format", "text/plain", `{"title": "Other"}`, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"failed test", "application/json-patch+json", `[{"op": "test", "path": "/title", "value": "Other"}, {"op": "replace", "path": "/title", "value": "Changed"}]`, http.StatusConflict, "patch_test_failed"},
+		{"test of assigned", "application/json-patch+json", fmt.Sprintf(`[{"op": "test", "path": "/assigned", "value": %q}]`, c.ID_contact), http.StatusBadRequest, "invalid_patch"},
+		{"invalid result", "application/json-patch+json", `[{"op": "replace", "path": "/title", "value": ""}]`, http.StatusUnprocessableEntity, "validation_failed"},
+		{"invalid patch", "application/merge-patch+json", `{"title": `, http.StatusBadRequest, "invalid_patch"},
+	}
+	for _, tt := range tests {
+		w := serveWith(patchTask, "PATCH", "/tasks/"+id, tt.patch, tt.contentType, "id", id)
+		if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.wantCode) {
+			t.Errorf("%s: got %d %s, want %d %s", tt.name, w.Code, w.Body, tt.want, tt.wantCode)
+		}
+	}
+	if stored := readTask(t, b, id); stored.Title != "Patch me" || stored.Version != task.Version {
+		t.Errorf("the failed patches changed the task to %q at version %d", stored.Title, stored.Version)
+	}
+
+	// Removing the only assignee must not bring it back through the `assigned` field it was served
+	// with.
+	w := serveWith(patchTask, "PATCH", "/tasks/"+id, `{"assignees": null}`, "application/merge-patch+json", "id", id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("removing the assignees: got %d %s", w.Code, w.Body)
+	}
+	if stored := readTask(t, b, id); len(stored.Assignees) != 0 {
+		t.Errorf("got assignees %v after removing them", stored.Assignees)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"sort"
+)
+
+// The `listUsers` function handles `GET /users`. It serves every account of the board with its role,
+// ordered by the time it was created, so that an admin can decide whose role to change.
+func listUsers(w http.ResponseWriter, r *http.Request) {
+	var allUsers map[string]*structures.User
+	err := store.View(func(tx storage.Tx) error {
+		var err error
+		allUsers, err = tx.Users()
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list := make([]userView, 0, len(allUsers))
+	for _, u := range allUsers {
+		list = append(list, viewOf(u))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Created.Equal(list[j].Created) {
+			return list[i].Created.Before(list[j].Created)
+		}
+		return list[i].ID_user < list[j].ID_user
+	})
+	writeJSON(w, http.StatusOK, list)
+}
+
+// The `setUserRole` function handles `PUT /users/{id}/role` with a body of the form {"role": "guest"}
+// and answers with the updated user. The last admin of the board cannot lose the admin role, because
+// nobody could give it back afterwards; trying results in 409 Conflict.
+func setUserRole(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var body struct {
+		Role structures.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return
+	}
+	if !body.Role.Valid() {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_role",
+			fmt.Sprintf("role %q is unknown; use one of %v", body.Role, structures.Roles))
+		return
+	}
+
+	var user *structures.User
+	err := store.Update(func(tx storage.Tx) error {
+		var err error
+		user, err = tx.User(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return &httpError{http.StatusNotFound, "not_found", fmt.Sprintf("user %q not found", id)}
+		}
+		if err != nil {
+			return err
+		}
+
+		if user.Role == structures.RoleAdmin && body.Role != structures.RoleAdmin {
+			users, err := tx.Users()
+			if err != nil {
+				return err
+			}
+			admins := 0
+			for _, u := range users {
+				if u.Role == structures.RoleAdmin {
+					admins++
+				}
+			}
+			if admins <= 1 {
+				return &httpError{http.StatusConflict, "last_admin", "the last admin of the board cannot lose the admin role"}
+			}
+		}
+
+		user.Role = body.Role
+		return tx.PutUser(user)
+	})
+	if err != nil {
+		writeStoreError(w, err, "Error writing users")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, viewOf(user))
+}
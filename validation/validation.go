@@ -0,0 +1,253 @@
+// Package validation checks tasks, contacts, categories and users before they are stored. Every
+// rule that fails adds a `FieldError` naming the offending JSON field, so that the frontend can
+// show all problems of a form at once instead of one per round trip.
+package validation
+
+import (
+	"fmt"
+	"minibackend/structures"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The `DateLayout` constant is the format of `Task.Due_date`, e.g. "2024-03-29".
+const DateLayout = "2006-01-02"
+
+// The `MaxTitleLength` constant limits the length of task and subtask titles and of category
+// names.
+const MaxTitleLength = 200
+
+// The `phonePattern` expression accepts phone numbers made of digits with an optional leading "+" and
+// the usual separators (spaces, dashes, slashes, dots and parentheses).
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ()/.\-]{5,25}$`)
+
+// The `FieldError` struct describes one failed rule. `Field` is the JSON name of the field, with an
+// index for list elements such as "subtasks[2].title".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// The `Errors` type collects the failed rules of one entity. It implements `error`, so it can be
+// returned from a store transaction and recognized with `errors.As` by the handler.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// The `add` method records a failed rule for `field`.
+func (e *Errors) add(field, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// The `Task` function checks `t` against the task rules. `categories` and `contacts` are the
+// existing categories and contacts, which the task may reference. It returns nil if the task is valid.
+func Task(t *structures.Task, categories map[string]*structures.Category, contacts map[string]*structures.Contact) Errors {
+	var errs Errors
+
+	checkTitle(&errs, "title", t.Title)
+
+	// Requests with an unknown status or priority are already rejected while decoding, but tasks read
+	// from old data files may still hold one; a task is only written with one of the allowed values.
+	switch {
+	case t.Status == "":
+		errs.add("status", "is required")
+	case !t.Status.Valid():
+		errs.add("status", "unknown status %q; use one of %v", t.Status, structures.Statuses)
+	}
+	switch {
+	case t.Prio == "":
+		errs.add("prio", "is required")
+	case !t.Prio.Valid():
+		errs.add("prio", "unknown priority %q; use one of %v", t.Prio, structures.Priorities)
+	}
+
+	// The due date is optional, but if it is set it has to be a real calendar date in the format the
+	// frontend's date picker produces.
+	if t.Due_date != "" {
+		if _, err := time.Parse(DateLayout, t.Due_date); err != nil {
+			errs.add("due_date", "must be a date in the format YYYY-MM-DD")
+		}
+	}
+
+	if t.Category == "" {
+		errs.add("category", "is required")
+	} else if _, ok := categories[t.Category]; !ok {
+		errs.add("category", "unknown category %q", t.Category)
+	}
+
+	seen := make(map[string]bool, len(t.Assignees))
+	for i, id := range t.Assignees {
+		field := fmt.Sprintf("assignees[%d]", i)
+		if _, ok := contacts[id]; !ok {
+			errs.add(field, "unknown contact %q", id)
+		} else if seen[id] {
+			errs.add(field, "contact %q is assigned twice", id)
+		}
+		seen[id] = true
+	}
+
+	for i, sub := range t.Subtasks {
+		checkTitle(&errs, fmt.Sprintf("subtasks[%d].title", i), sub.Title)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// The `Contact` function checks `c` against the contact rules. It returns nil if the contact is
+// valid.
+func Contact(c *structures.Contact) Errors {
+	var errs Errors
+
+	if strings.TrimSpace(c.First_Name) == "" && strings.TrimSpace(c.Last_Name) == "" {
+		errs.add("first_name", "first or last name is required")
+	}
+
+	// The email address is required because the contact list shows it. `mail.ParseAddress` also accepts
+	// forms like "Name <a@b.c>", so the parsed address has to match the input exactly.
+	if c.Email == "" {
+		errs.add("email", "is required")
+	} else if addr, err := mail.ParseAddress(c.Email); err != nil || addr.Address != c.Email {
+		errs.add("email", "must be a valid email address")
+	}
+
+	if c.Phone != "" && !phonePattern.MatchString(c.Phone) {
+		errs.add("phone", "must be a phone number made of digits, optionally starting with +")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// The `Category` function checks `c` against the category rules. `categories` are the existing
+// categories; the name of `c` must not be used by any of them except `c` itself, because the frontend
+// only shows the name. It returns nil if the category is valid.
+func Category(c *structures.Category, categories map[string]*structures.Category) Errors {
+	var errs Errors
+
+	checkTitle(&errs, "name", c.Name)
+	for id, other := range categories {
+		if id != c.ID_category && strings.EqualFold(strings.TrimSpace(other.Name), strings.TrimSpace(c.Name)) {
+			errs.add("name", "category %q already exists", other.Name)
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// The password length limits. bcrypt ignores everything after the 72nd byte, so longer passwords
+// are rejected instead of being silently truncated.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// The `User` function checks the account `u` with the plain-text `password` against the user rules.
+// `users` are the existing accounts; the email address of `u` must not be used by any of them except
+// `u` itself, because it is the login name. It returns nil if the account is valid.
+func User(u *structures.User, password string, users map[string]*structures.User) Errors {
+	var errs Errors
+
+	checkTitle(&errs, "name", u.Name)
+
+	if u.Email == "" {
+		errs.add("email", "is required")
+	} else if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+		errs.add("email", "must be a valid email address")
+	} else {
+		for id, other := range users {
+			if id != u.ID_user && strings.EqualFold(other.Email, u.Email) {
+				errs.add("email", "is already registered")
+				break
+			}
+		}
+	}
+
+	switch {
+	case len(password) < MinPasswordLength:
+		errs.add("password", "must be at least %d characters long", MinPasswordLength)
+	case len(password) > MaxPasswordLength:
+		errs.add("password", "must not be longer than %d bytes", MaxPasswordLength)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// The `Board` function checks the board `b` against the board rules: it needs a name, and every
+// member must be one of the `users`, listed once. It returns nil if the board is valid.
+func Board(b *structures.Board, users map[string]*structures.User) Errors {
+	var errs Errors
+
+	checkTitle(&errs, "name", b.Name)
+	seen := make(map[string]bool, len(b.Members))
+	for i, id := range b.Members {
+		field := fmt.Sprintf("members[%d]", i)
+		switch {
+		case users[id] == nil:
+			errs.add(field, "user %q not found", id)
+		case seen[id]:
+			errs.add(field, "user %q is listed twice", id)
+		}
+		seen[id] = true
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// The `APIKey` function checks the API key `k` against the key rules at the time `now`: it needs a
+// name, one of the `users` to act as, a known scope, and an expiry, if any, in the future. It returns
+// nil if the key is valid.
+func APIKey(k *structures.APIKey, users map[string]*structures.User, now time.Time) Errors {
+	var errs Errors
+
+	checkTitle(&errs, "name", k.Name)
+	switch {
+	case k.User == "":
+		errs.add("user", "is required")
+	case users[k.User] == nil:
+		errs.add("user", "user %q not found", k.User)
+	}
+	if !k.Scope.Valid() {
+		errs.add("scope", "must be %q or %q", structures.ScopeRead, structures.ScopeWrite)
+	}
+	if !k.Expires.IsZero() && !k.Expires.After(now) {
+		errs.add("expires_at", "must be in the future")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// The `checkTitle` function records an error if `title` is blank or too long.
+func checkTitle(errs *Errors, field, title string) {
+	switch {
+	case strings.TrimSpace(title) == "":
+		errs.add(field, "is required")
+	case len([]rune(title)) > MaxTitleLength:
+		errs.add(field, "must not be longer than %d characters", MaxTitleLength)
+	}
+}
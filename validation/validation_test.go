@@ -0,0 +1,171 @@
+package validation
+
+import (
+	"encoding/json"
+	"minibackend/structures"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The `sampleDir` constant is the data directory shipped with the repository, which the rules must
+// accept as it is.
+const sampleDir = "../data"
+
+// The `fields` function returns the fields named by `errs`, in order.
+func fields(errs Errors) []string {
+	var names []string
+	for _, fe := range errs {
+		names = append(names, fe.Field)
+	}
+	return names
+}
+
+// The `checkFields` function fails the test `name` unless `errs` names exactly the fields `want`.
+func checkFields(t *testing.T, name string, errs Errors, want ...string) {
+	t.Helper()
+	if got := strings.Join(fields(errs), ","); got != strings.Join(want, ",") {
+		t.Errorf("%s: got errors for [%s], want [%s] (%v)", name, got, strings.Join(want, ","), errs)
+	}
+}
+
+func TestTask(t *testing.T) {
+	categories := map[string]*structures.Category{"cat1": {ID_category: "cat1", Name: "Work"}}
+	contacts := map[string]*structures.Contact{
+		"c1": {ID_contact: "c1"},
+		"c2": {ID_contact: "c2"},
+	}
+	valid := func() *structures.Task {
+		return &structures.Task{
+			Title:     "Write tests",
+			Status:    structures.StatusToDo,
+			Prio:      structures.PriorityMedium,
+			Due_date:  "2024-03-29",
+			Category:  "cat1",
+			Assignees: []string{"c1", "c2"},
+			Subtasks:  []structures.Subtask{{Title: "First"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *structures.Task)
+		want   []string
+	}{
+		{"valid", func(t *structures.Task) {}, nil},
+		{"no due date", func(t *structures.Task) { t.Due_date = "" }, nil},
+		{"no assignees", func(t *structures.Task) { t.Assignees = nil }, nil},
+		{"blank title", func(t *structures.Task) { t.Title = "  " }, []string{"title"}},
+		{"long title", func(t *structures.Task) { t.Title = strings.Repeat("x", MaxTitleLength+1) }, []string{"title"}},
+		{"longest title", func(t *structures.Task) { t.Title = strings.Repeat("ä", MaxTitleLength) }, nil},
+		{"no status", func(t *structures.Task) { t.Status = "" }, []string{"status"}},
+		{"no prio", func(t *structures.Task) { t.Prio = "" }, []string{"prio"}},
+		{"unknown status", func(t *structures.Task) { t.Status = "Blocked" }, []string{"status"}},
+		{"unknown prio", func(t *structures.Task) { t.Prio = "Whenever" }, []string{"prio"}},
+		{"bad due date", func(t *structures.Task) { t.Due_date = "29.03.2024" }, []string{"due_date"}},
+		{"impossible due date", func(t *structures.Task) { t.Due_date = "2024-02-30" }, []string{"due_date"}},
+		{"no category", func(t *structures.Task) { t.Category = "" }, []string{"category"}},
+		{"unknown category", func(t *structures.Task) { t.Category = "cat9" }, []string{"category"}},
+		{"unknown assignee", func(t *structures.Task) { t.Assignees = []string{"c1", "c9"} }, []string{"assignees[1]"}},
+		{"assignee twice", func(t *structures.Task) { t.Assignees = []string{"c1", "c1"} }, []string{"assignees[1]"}},
+		{"blank subtask", func(t *structures.Task) { t.Subtasks = append(t.Subtasks, structures.Subtask{}) }, []string{"subtasks[1].title"}},
+		{"several problems", func(t *structures.Task) { t.Title, t.Category = "", "" }, []string{"title", "category"}},
+	}
+	for _, tt := range tests {
+		task := valid()
+		tt.change(task)
+		checkFields(t, tt.name, Task(task, categories, contacts), tt.want...)
+	}
+}
+
+func TestContact(t *testing.T) {
+	valid := func() *structures.Contact {
+		return &structures.Contact{First_Name: "Ada", Last_Name: "Lovelace", Email: "ada@example.com", Phone: "+49 (30) 123-456"}
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *structures.Contact)
+		want   []string
+	}{
+		{"valid", func(c *structures.Contact) {}, nil},
+		{"first name only", func(c *structures.Contact) { c.Last_Name = "" }, nil},
+		{"last name only", func(c *structures.Contact) { c.First_Name = "" }, nil},
+		{"no phone", func(c *structures.Contact) { c.Phone = "" }, nil},
+		{"no name", func(c *structures.Contact) { c.First_Name, c.Last_Name = " ", "" }, []string{"first_name"}},
+		{"no email", func(c *structures.Contact) { c.Email = "" }, []string{"email"}},
+		{"bad email", func(c *structures.Contact) { c.Email = "ada.example.com" }, []string{"email"}},
+		{"email with name", func(c *structures.Contact) { c.Email = "Ada <ada@example.com>" }, []string{"email"}},
+		{"letters in phone", func(c *structures.Contact) { c.Phone = "call me" }, []string{"phone"}},
+		{"short phone", func(c *structures.Contact) { c.Phone = "123" }, []string{"phone"}},
+	}
+	for _, tt := range tests {
+		contact := valid()
+		tt.change(contact)
+		checkFields(t, tt.name, Contact(contact), tt.want...)
+	}
+}
+
+func TestCategory(t *testing.T) {
+	categories := map[string]*structures.Category{
+		"cat1": {ID_category: "cat1", Name: "Work"},
+		"cat2": {ID_category: "cat2", Name: "Home"},
+	}
+
+	tests := []struct {
+		name     string
+		category structures.Category
+		want     []string
+	}{
+		{"new", structures.Category{ID_category: "cat3", Name: "Sales"}, nil},
+		{"unchanged name", structures.Category{ID_category: "cat1", Name: "Work"}, nil},
+		{"blank name", structures.Category{ID_category: "cat3", Name: " "}, []string{"name"}},
+		{"long name", structures.Category{ID_category: "cat3", Name: strings.Repeat("x", MaxTitleLength+1)}, []string{"name"}},
+		{"taken name", structures.Category{ID_category: "cat3", Name: " work "}, []string{"name"}},
+		{"rename to taken name", structures.Category{ID_category: "cat1", Name: "HOME"}, []string{"name"}},
+	}
+	for _, tt := range tests {
+		checkFields(t, tt.name, Category(&tt.category, categories), tt.want...)
+	}
+}
+
+// The `TestSampleData` test loads the JSON files of the sample data directory and checks that every
+// task, contact and category in them passes the rules.
+func TestSampleData(t *testing.T) {
+	var tasks map[string]*structures.Task
+	var contacts map[string]*structures.Contact
+	var names map[string]string
+	readSample(t, "tasks.json", &tasks)
+	readSample(t, "contacts.json", &contacts)
+	readSample(t, "categories.json", &names)
+	if len(tasks) == 0 || len(contacts) == 0 || len(names) == 0 {
+		t.Fatalf("sample data is incomplete: %d tasks, %d contacts, %d categories", len(tasks), len(contacts), len(names))
+	}
+
+	categories := make(map[string]*structures.Category, len(names))
+	for id, name := range names {
+		categories[id] = &structures.Category{ID_category: id, Name: name}
+	}
+	for id, c := range categories {
+		checkFields(t, "category "+id, Category(c, categories))
+	}
+	for id, c := range contacts {
+		checkFields(t, "contact "+id, Contact(c))
+	}
+	for id, task := range tasks {
+		checkFields(t, "task "+id, Task(task, categories, contacts))
+	}
+}
+
+// The `readSample` function decodes the file `name` of the sample data directory into `v`.
+func readSample(t *testing.T, name string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(sampleDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minibackend/collab"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The WebSocket timing: a ping is sent every `wsPingInterval`, and a connection that has not answered
+// within `wsPongWait` is closed.
+const (
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsWriteWait    = 10 * time.Second
+)
+
+// The `upgrader` variable accepts WebSocket connections from the origins allowed by the CORS settings,
+// because the Kanban frontend is served from a different host than the API, and from clients such as
+// scripts that send no Origin header at all.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || corsSettings.allowsOrigin(origin)
+	},
+}
+
+// The `wsHandler` function handles `GET /ws`, the collaboration channel of the board. The name shown
+// to the other clients is the name of the logged-in user, or the `user` query parameter when
+// authentication is disabled. Messages are JSON objects as described in the `collab` package: the
+// client sends "presence" and "move" messages and receives presence updates, errors and every change
+// to the board, e.g. "task.updated".
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	b := boardOf(r)
+	user := r.URL.Query().Get("user")
+	if u := currentUser(r); u != nil {
+		user = u.Name
+	}
+	if user == "" {
+		writeError(w, http.StatusBadRequest, "invalid_query", "user: the name shown to other clients is required")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the request with an error.
+		return
+	}
+	client := b.hub.Join(user)
+	go writeWS(conn, client)
+
+	// The read loop runs until the connection fails or is closed. A pong from the client extends the
+	// read deadline, so a dead connection is noticed within `wsPongWait`.
+	defer client.Leave()
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg collab.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			client.Reply(collab.Message{Type: collab.TypeError, Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+		client.Send(msg)
+	}
+}
+
+// The `writeWS` function writes the messages of `client` to `conn` and pings the client regularly.
+// It closes the connection once the client has left, which also ends the read loop of `wsHandler`.
+func writeWS(conn *websocket.Conn, client *collab.Client) {
+	ping := time.NewTicker(wsPingInterval)
+	defer func() {
+		ping.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-client.Messages():
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				client.Leave()
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				client.Leave()
+				return
+			}
+		}
+	}
+}
+
+// The `forwardEvents` function passes every event of the event bus of `b` on to the clients of its
+// hub. It runs for the lifetime of the server; if the bus drops it for falling behind, it subscribes
+// again and resumes after the last event it forwarded.
+func forwardEvents(b *board) {
+	var lastID uint64
+	for {
+		sub, missed, _, cancel := b.bus.Subscribe(lastID)
+		for _, e := range missed {
+			b.hub.Broadcast(collab.Message{Type: e.Type, ID: e.ID, Data: e.Data})
+			lastID = e.ID
+		}
+		for e := range sub {
+			b.hub.Broadcast(collab.Message{Type: e.Type, ID: e.ID, Data: e.Data})
+			lastID = e.ID
+		}
+		cancel()
+	}
+}
+
+// The `moveTask` function applies a card move sent over the WebSocket of the board `b`: the task is
+// moved to the column `move.Status` and saved through `saveTask`, the same path as `updateTask`. The
+// move is only applied if the task has not changed since the version the client sent, or since it was
+// loaded if the client sent none, so a move never overwrites a concurrent edit.
+func moveTask(b *board, user string, move collab.Message) error {
+	status, err := structures.ParseStatus(move.Status)
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return errors.New("the move needs a status")
+	}
+
+	var task *structures.Task
+	err = b.store.View(func(tx storage.Tx) error {
+		var err error
+		task, err = tx.Task(move.TaskID)
+		return err
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return errTaskNotFound(move.TaskID)
+	}
+	if err != nil {
+		return err
+	}
+
+	version := task.Version
+	if move.Version != 0 {
+		version = move.Version
+	}
+	task.Status = status
+	if _, err := saveTask(b, task, false, etag(version)); err != nil {
+		return fmt.Errorf("moving task %s failed: %w", move.TaskID, err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"minibackend/collab"
+	"minibackend/storage"
+	"minibackend/structures"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The `TestWebSocket` test connects to `GET /ws` over a real HTTP server, moves a card through the
+// socket and checks that the move is stored and comes back as a "task.updated" event.
+func TestWebSocket(t *testing.T) {
+	b := newTestBoard(t)
+	if w := serve(replaceTask, "PUT", "/tasks/tk-ws?upsert=true", taskJSON("Move me"), "id", "tk-ws"); w.Code != http.StatusCreated {
+		t.Fatalf("creating the task: got %d %s", w.Code, w.Body)
+	}
+	server := httptest.NewServer(http.HandlerFunc(wsHandler))
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("without a user: got %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?user=Ada"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	read := func() collab.Message {
+		t.Helper()
+		var msg collab.Message
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	if msg := read(); msg.Type != collab.TypeWelcome || msg.User != "Ada" {
+		t.Fatalf("got %+v, want the welcome of Ada", msg)
+	}
+
+	// A move of an unknown task is answered with an error to the sender only.
+	if err := conn.WriteJSON(collab.Message{Type: collab.TypeMove, TaskID: "tk-none", Status: "Done"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := read(); msg.Type != collab.TypeError || msg.TaskID != "tk-none" {
+		t.Errorf("got %+v, want an error for tk-none", msg)
+	}
+
+	if err := conn.WriteJSON(collab.Message{Type: collab.TypeMove, TaskID: "tk-ws", Status: "Done"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := read(); msg.Type != "task.updated" {
+		t.Errorf("got %+v, want task.updated", msg)
+	}
+	var task *structures.Task
+	err = b.store.View(func(tx storage.Tx) error {
+		var err error
+		task, err = tx.Task("tk-ws")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != structures.StatusDone || task.Version != 2 {
+		t.Errorf("got status %s version %d, want Done version 2", task.Status, task.Version)
+	}
+}